@@ -0,0 +1,211 @@
+// Package archive extracts the zip and tar bundles the datasources download, without letting an entry be written
+// outside of the target folder.
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Formats, by the file name endings that identify them.
+const (
+	Zip    = "zip"
+	Tar    = "tar"
+	TarGz  = "tar.gz"
+	TarZst = "tar.zst"
+)
+
+// maxSize caps the bytes an archive may unpack to, all entries together, so a small archive that expands to far
+// more than it claims (a zip bomb) fails instead of filling the disk. The sizes an archive records aren't trusted;
+// the bytes are counted as they are written.
+var maxSize int64 = 64 << 30
+
+var suffixes = []struct {
+	suffix, format string
+}{
+	{".zip", Zip},
+	{".tar", Tar},
+	{".tar.gz", TarGz},
+	{".tgz", TarGz},
+	{".tar.zst", TarZst},
+	{".tzst", TarZst},
+}
+
+// Format returns the archive format of the file name, or an empty string if it isn't an archive.
+func Format(name string) string {
+	name = strings.ToLower(name)
+	for _, s := range suffixes {
+		if strings.HasSuffix(name, s.suffix) {
+			return s.format
+		}
+	}
+	return ""
+}
+
+// Extract unpacks the archive into destDir, creating it if needed, and returns the paths of the files extracted,
+// sorted. Existing files are overwritten. An entry that would land outside of destDir (ex: ../../etc/passwd or
+// an absolute path) fails the extraction; symbolic and hard links are skipped, since they could point outside.
+// Unpacking more than maxSize bytes fails it as well.
+func Extract(archivePath, destDir string) ([]string, error) {
+	var files []string
+	var err error
+	switch Format(archivePath) {
+	case Zip:
+		files, err = extractZip(archivePath, destDir)
+	case Tar, TarGz, TarZst:
+		files, err = extractTarFile(archivePath, destDir)
+	default:
+		return nil, fmt.Errorf("%s is not a zip, tar, tar.gz, or tar.zst archive", archivePath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Unable to extract %s: %s", archivePath, err)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// target returns where the entry belongs in destDir, or an error if that is outside of it.
+func target(destDir, name string) (string, error) {
+	localPath := filepath.FromSlash(strings.TrimSuffix(name, "/"))
+	if !filepath.IsLocal(localPath) {
+		return "", fmt.Errorf("the entry %q would be extracted outside of %s", name, destDir)
+	}
+	return filepath.Join(destDir, localPath), nil
+}
+
+// writeFile saves the entry's content, making it executable if the entry was. remaining is how many bytes the
+// archive may still unpack to; it is reduced by the size of the file.
+func writeFile(path string, content io.Reader, mode fs.FileMode, remaining *int64) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	perm := fs.FileMode(0644)
+	if mode&0111 != 0 {
+		perm = 0755
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	written, err := io.CopyN(file, content, *remaining+1)
+	if err == io.EOF {
+		err = nil
+	}
+	*remaining -= written
+	if err == nil && *remaining < 0 {
+		err = fmt.Errorf("the archive unpacks to more than %d bytes", maxSize)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func extractZip(archivePath, destDir string) ([]string, error) {
+	reader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	var files []string
+	remaining := maxSize
+	for _, entry := range reader.File {
+		path, err := target(destDir, entry.Name)
+		if err != nil {
+			return files, err
+		}
+		mode := entry.Mode()
+		switch {
+		case mode.IsDir():
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return files, err
+			}
+		case mode&fs.ModeSymlink != 0:
+			log.Printf("[WARN] Skipped the link %s in %s", entry.Name, archivePath)
+		default:
+			content, err := entry.Open()
+			if err != nil {
+				return files, err
+			}
+			err = writeFile(path, content, mode, &remaining)
+			content.Close()
+			if err != nil {
+				return files, err
+			}
+			files = append(files, path)
+		}
+	}
+	return files, nil
+}
+
+func extractTarFile(archivePath, destDir string) ([]string, error) {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var stream io.Reader = file
+	switch Format(archivePath) {
+	case TarGz:
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		stream = gz
+	case TarZst:
+		zst, err := zstd.NewReader(file)
+		if err != nil {
+			return nil, err
+		}
+		defer zst.Close()
+		stream = zst
+	}
+	return extractTar(tar.NewReader(stream), archivePath, destDir)
+}
+
+func extractTar(reader *tar.Reader, archivePath, destDir string) ([]string, error) {
+	var files []string
+	remaining := maxSize
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return files, err
+		}
+		path, err := target(destDir, header.Name)
+		if err != nil {
+			return files, err
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return files, err
+			}
+		case tar.TypeReg:
+			if err := writeFile(path, reader, header.FileInfo().Mode(), &remaining); err != nil {
+				return files, err
+			}
+			files = append(files, path)
+		case tar.TypeSymlink, tar.TypeLink:
+			log.Printf("[WARN] Skipped the link %s in %s", header.Name, archivePath)
+		default:
+			log.Printf("[WARN] Skipped %s in %s; only files and folders are extracted", header.Name, archivePath)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+type entry struct {
+	name, content string
+	link          bool
+}
+
+var bundle = []entry{
+	{name: "drivers/"},
+	{name: "drivers/nic.inf", content: "nic driver"},
+	{name: "setup.ps1", content: "Write-Host setup"},
+}
+
+func zipArchive(t *testing.T, entries []entry) []byte {
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for _, e := range entries {
+		header := &zip.FileHeader{Name: e.name}
+		if e.link {
+			header.SetMode(os.ModeSymlink | 0777)
+		}
+		w, err := writer.CreateHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(e.content))
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func tarArchive(t *testing.T, entries []entry) []byte {
+	var buf bytes.Buffer
+	writer := tar.NewWriter(&buf)
+	for _, e := range entries {
+		header := &tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.content)), Typeflag: tar.TypeReg}
+		switch {
+		case e.link:
+			header = &tar.Header{Name: e.name, Linkname: e.content, Typeflag: tar.TypeSymlink}
+		case strings.HasSuffix(e.name, "/"):
+			header = &tar.Header{Name: e.name, Mode: 0755, Typeflag: tar.TypeDir}
+		}
+		if err := writer.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if header.Typeflag == tar.TypeReg {
+			writer.Write([]byte(e.content))
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// build writes the entries as an archive of the format the name calls for.
+func build(t *testing.T, name string, entries []entry) string {
+	var content []byte
+	switch Format(name) {
+	case Zip:
+		content = zipArchive(t, entries)
+	case Tar:
+		content = tarArchive(t, entries)
+	case TarGz:
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		gz.Write(tarArchive(t, entries))
+		gz.Close()
+		content = buf.Bytes()
+	case TarZst:
+		encoder, err := zstd.NewWriter(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content = encoder.EncodeAll(tarArchive(t, entries), nil)
+	}
+	archivePath := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(archivePath, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return archivePath
+}
+
+func TestFormat(t *testing.T) {
+	tests := map[string]string{
+		"tools.zip": Zip, "tools.TAR": Tar, "tools.tar.gz": TarGz, "tools.tgz": TarGz,
+		"tools.tar.zst": TarZst, "tools.tzst": TarZst, "tools.gz": "", "tools.exe": "",
+	}
+	for name, want := range tests {
+		if got := Format(name); got != want {
+			t.Errorf("Format(%s) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestExtract(t *testing.T) {
+	for _, name := range []string{"bundle.zip", "bundle.tar", "bundle.tar.gz", "bundle.tar.zst"} {
+		t.Run(name, func(t *testing.T) {
+			destDir := filepath.Join(t.TempDir(), "extracted")
+			entries := append(bundle, entry{name: "escape", content: "../../outside", link: true})
+
+			files, err := Extract(build(t, name, entries), destDir)
+			if err != nil {
+				t.Fatalf("Extract() error = %s", err)
+			}
+			want := []string{filepath.Join(destDir, "drivers", "nic.inf"), filepath.Join(destDir, "setup.ps1")}
+			if strings.Join(files, ",") != strings.Join(want, ",") {
+				t.Errorf("Extract() = %v, want %v", files, want)
+			}
+			if got, _ := os.ReadFile(want[0]); string(got) != "nic driver" {
+				t.Errorf("nic.inf = %q", got)
+			}
+			if _, err := os.Lstat(filepath.Join(destDir, "escape")); !os.IsNotExist(err) {
+				t.Error("links should be skipped")
+			}
+		})
+	}
+}
+
+func TestExtract_PathTraversal(t *testing.T) {
+	for _, name := range []string{"../../outside.txt", "drivers/../../outside.txt", "/etc/outside.txt"} {
+		for _, archiveName := range []string{"evil.zip", "evil.tar.gz"} {
+			root := t.TempDir()
+			destDir := filepath.Join(root, "a", "b")
+
+			_, err := Extract(build(t, archiveName, []entry{{name: name, content: "gotcha"}}), destDir)
+			if err == nil || !strings.Contains(err.Error(), "outside of") {
+				t.Errorf("Extract() of %s in %s error = %v, want a path traversal error", name, archiveName, err)
+			}
+			if _, err := os.Stat(filepath.Join(root, "outside.txt")); !os.IsNotExist(err) {
+				t.Errorf("%s in %s was written outside of the target folder", name, archiveName)
+			}
+		}
+	}
+}
+
+func TestExtract_TooLarge(t *testing.T) {
+	defer func(size int64) { maxSize = size }(maxSize)
+	maxSize = int64(len("nic driver")+len("Write-Host setup")) - 1
+
+	for _, name := range []string{"bundle.zip", "bundle.tar.zst"} {
+		destDir := t.TempDir()
+		if _, err := Extract(build(t, name, bundle), destDir); err == nil || !strings.Contains(err.Error(), "more than") {
+			t.Errorf("Extract() of %s error = %v, want a size error", name, err)
+		}
+	}
+
+	maxSize++
+	if _, err := Extract(build(t, "bundle.tar", bundle), t.TempDir()); err != nil {
+		t.Errorf("Extract() of an archive within the limit error = %s", err)
+	}
+}
+
+func TestExtract_NotAnArchive(t *testing.T) {
+	if _, err := Extract(filepath.Join(t.TempDir(), "setup.exe"), t.TempDir()); err == nil {
+		t.Error("expected an error for a file that isn't an archive")
+	}
+}
@@ -0,0 +1,79 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// AqlItem is a single result of an Artifactory Query Language search.
+type AqlItem struct {
+	Repo       string        `json:"repo"`
+	Path       string        `json:"path"`
+	Name       string        `json:"name"`
+	Type       string        `json:"type"`
+	Size       int64         `json:"size"`
+	Created    string        `json:"created"`
+	CreatedBy  string        `json:"created_by"`
+	Modified   string        `json:"modified"`
+	ModifiedBy string        `json:"modified_by"`
+	Updated    string        `json:"updated"`
+	Sha256     string        `json:"sha256"`
+	ActualSha1 string        `json:"actual_sha1"`
+	ActualMd5  string        `json:"actual_md5"`
+	Properties []AqlProperty `json:"properties"`
+}
+
+// AqlProperty is one key/value pair of an AqlItem; a key with several values appears once per value.
+type AqlProperty struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// RepoPath returns the /repo/folder/file path of the item.
+func (i AqlItem) RepoPath() string {
+	if i.Path == "" || i.Path == "." {
+		return "/" + i.Repo + "/" + i.Name
+	}
+	return "/" + i.Repo + "/" + i.Path + "/" + i.Name
+}
+
+// CreatedTime parses the creation date Artifactory returns (ex: 2024-01-05T14:21:03.120Z).
+func (i AqlItem) CreatedTime() (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, i.Created)
+}
+
+// SearchAql runs the query (ex: items.find({"repo":"images"})) against api/search/aql and returns the matching items.
+func (c *Client) SearchAql(ctx context.Context, query string) ([]AqlItem, error) {
+	request, err := c.NewRequest(ctx, http.MethodPost, c.ApiUrl("search/aql"), strings.NewReader(query))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "text/plain")
+
+	response, err := c.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to run the AQL search: %s", err)
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read the AQL search results: %s", err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("AQL search failed with status %d: %s", response.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var result struct {
+		Results []AqlItem `json:"results"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("Unable to parse the AQL search results: %s", err)
+	}
+	return result.Results, nil
+}
@@ -0,0 +1,42 @@
+package client
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"packer-plugin-artifactory/internal/testutil/fakeartifactory"
+)
+
+func TestSearchAql(t *testing.T) {
+	server := fakeartifactory.New(t)
+	server.AddArtifact("/images/win22.ova", []byte("root"), map[string]string{"release": "stable"})
+	server.AddArtifact("/images/win22/win22.ova", []byte("nested"), nil)
+
+	c := New(&ConnectionConfig{ArtifactoryToken: fakeartifactory.Token, ArtifactoryServer: server.ApiUrl()})
+
+	items, err := c.SearchAql(context.Background(), `items.find({"repo":"images"}).include("*","property").sort({"$asc":["created"]})`)
+	if err != nil {
+		t.Fatalf("SearchAql() error = %s", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(items))
+	}
+	if items[0].RepoPath() != "/images/win22.ova" || items[1].RepoPath() != "/images/win22/win22.ova" {
+		t.Errorf("unexpected paths %s, %s", items[0].RepoPath(), items[1].RepoPath())
+	}
+	if items[0].Size != 4 || items[0].Sha256 == "" {
+		t.Errorf("unexpected size/checksum %d %q", items[0].Size, items[0].Sha256)
+	}
+	if len(items[0].Properties) != 1 || items[0].Properties[0] != (AqlProperty{"release", "stable"}) {
+		t.Errorf("unexpected properties %v", items[0].Properties)
+	}
+	if _, err := items[0].CreatedTime(); err != nil {
+		t.Errorf("CreatedTime() error = %s", err)
+	}
+
+	_, err = c.SearchAql(context.Background(), `builds.find()`)
+	if err == nil || !strings.Contains(err.Error(), "status 400") {
+		t.Errorf("expected a 400 for an unsupported query, got %v", err)
+	}
+}
@@ -0,0 +1,272 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Artifact is a single file in Artifactory along with its metadata and properties.
+type Artifact struct {
+	Repo string
+	// Folder path within the repository, without leading or trailing slashes; empty for the repository root
+	Path string
+	Name string
+
+	// Dates as Artifactory reports them, ex: 2024-01-05T14:21:03.120Z
+	Created    string
+	CreatedBy  string
+	Modified   string
+	ModifiedBy string
+	Size       int64
+
+	Sha256 string
+	Sha1   string
+	Md5    string
+
+	Properties map[string][]string
+}
+
+// RepoPath returns the /repo/folder/file path of the artifact.
+func (a Artifact) RepoPath() string {
+	if a.Path == "" {
+		return "/" + a.Repo + "/" + a.Name
+	}
+	return "/" + a.Repo + "/" + a.Path + "/" + a.Name
+}
+
+// CreatedTime parses the creation date.
+func (a Artifact) CreatedTime() (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, a.Created)
+}
+
+// ModifiedTime parses the last modified date.
+func (a Artifact) ModifiedTime() (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, a.Modified)
+}
+
+// Artifact converts the AQL result; fields the query did not include are left empty.
+func (i AqlItem) Artifact() Artifact {
+	artifact := Artifact{
+		Repo:       i.Repo,
+		Path:       strings.Trim(i.Path, "/"),
+		Name:       i.Name,
+		Created:    i.Created,
+		CreatedBy:  i.CreatedBy,
+		Modified:   i.Modified,
+		ModifiedBy: i.ModifiedBy,
+		Size:       i.Size,
+		Sha256:     i.Sha256,
+		Sha1:       i.ActualSha1,
+		Md5:        i.ActualMd5,
+		Properties: map[string][]string{},
+	}
+	if artifact.Path == "." {
+		artifact.Path = ""
+	}
+	for _, prop := range i.Properties {
+		artifact.Properties[prop.Key] = append(artifact.Properties[prop.Key], prop.Value)
+	}
+	return artifact
+}
+
+// repoPathFromUri returns the /repo/folder/file path behind a storage API URI.
+func repoPathFromUri(uri string) (string, error) {
+	_, repoPath, found := strings.Cut(uri, "/api/storage/")
+	if !found {
+		return "", fmt.Errorf("%s is not a storage API URI", uri)
+	}
+	if unescaped, err := url.PathUnescape(repoPath); err == nil {
+		repoPath = unescaped
+	}
+	return "/" + repoPath, nil
+}
+
+// getJSON sends a GET request and decodes a 200 response into result. Returns the status code.
+func (c *Client) getJSON(ctx context.Context, url string, result interface{}) (int, error) {
+	request, err := c.NewRequest(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	response, err := c.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return response.StatusCode, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return response.StatusCode, fmt.Errorf("GET %s returned status %d: %s", url, response.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return response.StatusCode, json.Unmarshal(body, result)
+}
+
+// SearchByName runs the quick search (api/search/artifact), which matches file names containing the given name
+// without regard to case. Only the given repositories are searched, unless none are given. Returns the
+// /repo/folder/file path of every match.
+func (c *Client) SearchByName(ctx context.Context, name string, repos []string) ([]string, error) {
+	var result struct {
+		Results []struct {
+			Uri string `json:"uri"`
+		} `json:"results"`
+	}
+	query := "search/artifact?name=" + url.QueryEscape(name)
+	if len(repos) > 0 {
+		query += "&repos=" + url.QueryEscape(strings.Join(repos, ","))
+	}
+	if _, err := c.getJSON(ctx, c.ApiUrl(query), &result); err != nil {
+		return nil, fmt.Errorf("Unable to search for artifacts named %q: %s", name, err)
+	}
+
+	var repoPaths []string
+	for _, item := range result.Results {
+		repoPath, err := repoPathFromUri(item.Uri)
+		if err != nil {
+			return nil, err
+		}
+		repoPaths = append(repoPaths, repoPath)
+	}
+	return repoPaths, nil
+}
+
+// GetProperties returns every property assigned to the artifact; an artifact without properties returns an empty map.
+func (c *Client) GetProperties(ctx context.Context, repoPath string) (map[string][]string, error) {
+	var result struct {
+		Properties map[string][]string `json:"properties"`
+	}
+	status, err := c.getJSON(ctx, c.StorageUrl(repoPath)+"?properties", &result)
+	if status == http.StatusNotFound {
+		return map[string][]string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get the properties of %s: %s", repoPath, err)
+	}
+	if result.Properties == nil {
+		result.Properties = map[string][]string{}
+	}
+	return result.Properties, nil
+}
+
+// storageInfo is the storage API's description of a file.
+type storageInfo struct {
+	Repo         string `json:"repo"`
+	Path         string `json:"path"`
+	Created      string `json:"created"`
+	CreatedBy    string `json:"createdBy"`
+	LastModified string `json:"lastModified"`
+	ModifiedBy   string `json:"modifiedBy"`
+	Size         string `json:"size"`
+	Checksums    struct {
+		Sha256 string `json:"sha256"`
+		Sha1   string `json:"sha1"`
+		Md5    string `json:"md5"`
+	} `json:"checksums"`
+}
+
+// GetArtifact returns the metadata and properties of the artifact at the /repo/folder/file path.
+func (c *Client) GetArtifact(ctx context.Context, repoPath string) (Artifact, error) {
+	var info storageInfo
+	if _, err := c.getJSON(ctx, c.StorageUrl(repoPath), &info); err != nil {
+		return Artifact{}, fmt.Errorf("Unable to get the details of %s: %s", repoPath, err)
+	}
+
+	folder, name := "", strings.Trim(info.Path, "/")
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		folder, name = name[:i], name[i+1:]
+	}
+	size, _ := strconv.ParseInt(info.Size, 10, 64)
+
+	props, err := c.GetProperties(ctx, repoPath)
+	if err != nil {
+		return Artifact{}, err
+	}
+
+	return Artifact{
+		Repo:       info.Repo,
+		Path:       folder,
+		Name:       name,
+		Created:    info.Created,
+		CreatedBy:  info.CreatedBy,
+		Modified:   info.LastModified,
+		ModifiedBy: info.ModifiedBy,
+		Size:       size,
+		Sha256:     info.Checksums.Sha256,
+		Sha1:       info.Checksums.Sha1,
+		Md5:        info.Checksums.Md5,
+		Properties: props,
+	}, nil
+}
+
+// FileInfo is what a download needs to know about a file: where it is, its size, and its checksums.
+type FileInfo struct {
+	// /repo/folder/file path
+	RepoPath string
+	Size     int64
+	// Empty for files stored before Artifactory started computing sha256
+	Sha256   string
+	Sha1     string
+	Modified string
+}
+
+// StatFile returns the size and checksums of the file at the /repo/folder/file path.
+func (c *Client) StatFile(ctx context.Context, repoPath string) (FileInfo, error) {
+	var info storageInfo
+	if _, err := c.getJSON(ctx, c.StorageUrl(repoPath), &info); err != nil {
+		return FileInfo{}, fmt.Errorf("Unable to get the details of %s: %s", repoPath, err)
+	}
+	size, _ := strconv.ParseInt(info.Size, 10, 64)
+	return FileInfo{
+		RepoPath: repoPath,
+		Size:     size,
+		Sha256:   info.Checksums.Sha256,
+		Sha1:     info.Checksums.Sha1,
+		Modified: info.LastModified,
+	}, nil
+}
+
+// ListFiles returns the files in the /repo/folder path, along with their sizes and checksums, using the file list
+// API. With deep, files in every folder below it are included as well.
+func (c *Client) ListFiles(ctx context.Context, folder string, deep bool) ([]FileInfo, error) {
+	var result struct {
+		Files []struct {
+			Uri          string `json:"uri"`
+			Size         int64  `json:"size"`
+			LastModified string `json:"lastModified"`
+			Folder       bool   `json:"folder"`
+			Sha1         string `json:"sha1"`
+			Sha2         string `json:"sha2"`
+		} `json:"files"`
+	}
+	query := "?list&listFolders=0&deep=0"
+	if deep {
+		query = "?list&listFolders=0&deep=1"
+	}
+	folder = "/" + strings.Trim(folder, "/")
+	if _, err := c.getJSON(ctx, c.StorageUrl(folder)+query, &result); err != nil {
+		return nil, fmt.Errorf("Unable to list the files in %s: %s", folder, err)
+	}
+
+	var files []FileInfo
+	for _, file := range result.Files {
+		if file.Folder {
+			continue
+		}
+		files = append(files, FileInfo{
+			RepoPath: folder + "/" + strings.TrimLeft(file.Uri, "/"),
+			Size:     file.Size,
+			Sha256:   file.Sha2,
+			Sha1:     file.Sha1,
+			Modified: file.LastModified,
+		})
+	}
+	return files, nil
+}
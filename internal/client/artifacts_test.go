@@ -0,0 +1,92 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"packer-plugin-artifactory/internal/testutil/fakeartifactory"
+)
+
+func TestSearchByNameAndGetArtifact(t *testing.T) {
+	server := fakeartifactory.New(t)
+	server.AddArtifact("/images/win22/WIN22.ova", []byte("ova"), map[string]string{"release": "stable"})
+	server.AddArtifact("/images/rhel9.ova", []byte("rhel"), nil)
+
+	c := New(&ConnectionConfig{ArtifactoryToken: fakeartifactory.Token, ArtifactoryServer: server.ApiUrl()})
+	ctx := context.Background()
+
+	server.AddArtifact("/scratch/win22-test.ova", []byte("test"), nil)
+
+	repoPaths, err := c.SearchByName(ctx, "win22", []string{"images", "other"})
+	if err != nil {
+		t.Fatalf("SearchByName() error = %s", err)
+	}
+	if len(repoPaths) != 1 || repoPaths[0] != "/images/win22/WIN22.ova" {
+		t.Fatalf("SearchByName() = %v", repoPaths)
+	}
+	if all, _ := c.SearchByName(ctx, "win22", nil); len(all) != 2 {
+		t.Errorf("SearchByName() without repositories = %v, want both repositories", all)
+	}
+
+	artifact, err := c.GetArtifact(ctx, repoPaths[0])
+	if err != nil {
+		t.Fatalf("GetArtifact() error = %s", err)
+	}
+	if artifact.Repo != "images" || artifact.Path != "win22" || artifact.Name != "WIN22.ova" || artifact.RepoPath() != repoPaths[0] {
+		t.Errorf("unexpected location %+v", artifact)
+	}
+	if artifact.Size != 3 || artifact.Sha256 == "" || artifact.Created == "" {
+		t.Errorf("unexpected details %+v", artifact)
+	}
+	if got := artifact.Properties["release"]; len(got) != 1 || got[0] != "stable" {
+		t.Errorf("unexpected properties %v", artifact.Properties)
+	}
+
+	// No properties is a 404 from Artifactory
+	artifact, err = c.GetArtifact(ctx, "/images/rhel9.ova")
+	if err != nil {
+		t.Fatalf("GetArtifact() error = %s", err)
+	}
+	if artifact.Path != "" || len(artifact.Properties) != 0 {
+		t.Errorf("unexpected artifact %+v", artifact)
+	}
+
+	if _, err := c.GetArtifact(ctx, "/images/missing.ova"); err == nil {
+		t.Error("expected an error for a missing artifact")
+	}
+}
+
+func TestStatAndListFiles(t *testing.T) {
+	server := fakeartifactory.New(t)
+	server.AddArtifact("/images/win22/win22.ovf", []byte("ovf"), nil)
+	server.AddArtifact("/images/win22/win22-disk1.vmdk", []byte("disk"), nil)
+	server.AddArtifact("/images/win22/old/win22.ovf", []byte("old"), nil)
+
+	c := New(&ConnectionConfig{ArtifactoryToken: fakeartifactory.Token, ArtifactoryServer: server.ApiUrl()})
+	ctx := context.Background()
+
+	info, err := c.StatFile(ctx, "/images/win22/win22-disk1.vmdk")
+	if err != nil {
+		t.Fatalf("StatFile() error = %s", err)
+	}
+	item, _ := server.Artifact("/images/win22/win22-disk1.vmdk")
+	if info.RepoPath != "/images/win22/win22-disk1.vmdk" || info.Size != 4 || info.Sha256 != item.Sha256() || info.Sha1 != item.Sha1() {
+		t.Errorf("StatFile() = %+v", info)
+	}
+
+	files, err := c.ListFiles(ctx, "/images/win22", false)
+	if err != nil {
+		t.Fatalf("ListFiles() error = %s", err)
+	}
+	if len(files) != 2 {
+		t.Errorf("ListFiles() = %+v, want the two files directly in the folder", files)
+	}
+	for _, file := range files {
+		if file.Sha256 == "" || file.Size == 0 {
+			t.Errorf("ListFiles() file without details %+v", file)
+		}
+	}
+	if deep, _ := c.ListFiles(ctx, "/images/win22", true); len(deep) != 3 {
+		t.Errorf("ListFiles() deep = %+v, want all three files", deep)
+	}
+}
@@ -0,0 +1,103 @@
+// Package client owns the connection to an Artifactory server for every datasource and post-processor.
+// It resolves credentials, normalizes the server address, and holds a single reusable HTTP client.
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Client talks to a single Artifactory instance.
+type Client struct {
+	// API address of the server, ex: https://server.com:8081/artifactory/api
+	ServerApi string
+
+	token      string
+	httpClient *http.Client
+}
+
+// New returns a Client for the given connection settings. Prepare should have been called on the config first.
+func New(config *ConnectionConfig) *Client {
+	return &Client{
+		ServerApi:  NormalizeServerApi(config.ArtifactoryServer),
+		token:      config.ArtifactoryToken,
+		httpClient: &http.Client{},
+	}
+}
+
+// NormalizeServerApi trims whitespace and trailing slashes from the server address and makes sure it ends in '/api'.
+// Both 'https://server.com:8081/artifactory' and 'https://server.com:8081/artifactory/api/' become
+// 'https://server.com:8081/artifactory/api'.
+func NormalizeServerApi(server string) string {
+	server = strings.TrimRight(strings.TrimSpace(server), "/")
+	if server == "" {
+		return ""
+	}
+	if !strings.HasSuffix(server, "/api") {
+		server = server + "/api"
+	}
+	return server
+}
+
+// BaseUrl is the server address without the trailing '/api'; download URIs hang off of this.
+func (c *Client) BaseUrl() string {
+	return strings.TrimSuffix(c.ServerApi, "/api")
+}
+
+// ApiUrl joins the given path onto the API address.
+func (c *Client) ApiUrl(path string) string {
+	return c.ServerApi + "/" + strings.TrimLeft(path, "/")
+}
+
+// StorageUrl returns the artifact (storage API) URI for a /repo/folder/file path.
+func (c *Client) StorageUrl(repoPath string) string {
+	return c.ApiUrl("storage/" + EscapePath(repoPath))
+}
+
+// DownloadUrl returns the download URI for a /repo/folder/file path.
+func (c *Client) DownloadUrl(repoPath string) string {
+	return c.BaseUrl() + "/" + EscapePath(repoPath)
+}
+
+// EscapePath escapes each folder and file name of a /repo/folder/file path for use in a URL, without the leading
+// slash, ex: /images/win 22#1.ova becomes images/win%2022%231.ova.
+func EscapePath(repoPath string) string {
+	segments := strings.Split(strings.TrimLeft(repoPath, "/"), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// RepoPathFromDownloadUrl returns the /repo/folder/file path behind a download URI on this server. Storage URIs
+// (.../api/storage/repo/folder/file) are accepted as well.
+func (c *Client) RepoPathFromDownloadUrl(downloadUri string) (string, error) {
+	repoPath, found := strings.CutPrefix(strings.TrimSpace(downloadUri), c.BaseUrl()+"/")
+	repoPath = strings.TrimPrefix(repoPath, "api/storage/")
+	if !found || repoPath == "" {
+		return "", fmt.Errorf("%s is not a download URI on the Artifactory server %s", downloadUri, c.BaseUrl())
+	}
+	if unescaped, err := url.PathUnescape(repoPath); err == nil {
+		repoPath = unescaped
+	}
+	return "/" + repoPath, nil
+}
+
+// NewRequest builds an authenticated request against the server.
+func (c *Client) NewRequest(ctx context.Context, method, url string, body io.Reader) (*http.Request, error) {
+	request, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Authorization", "Bearer "+c.token)
+	return request, nil
+}
+
+// Do sends the request with the shared HTTP client.
+func (c *Client) Do(request *http.Request) (*http.Response, error) {
+	return c.httpClient.Do(request)
+}
@@ -0,0 +1,110 @@
+package client
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNormalizeServerApi(t *testing.T) {
+	tests := []struct {
+		server string
+		want   string
+	}{
+		{"https://server.com:8081/artifactory/api", "https://server.com:8081/artifactory/api"},
+		{"https://server.com:8081/artifactory/api/", "https://server.com:8081/artifactory/api"},
+		{"https://server.com:8081/artifactory", "https://server.com:8081/artifactory/api"},
+		{" https://company.jfrog.io/artifactory/ ", "https://company.jfrog.io/artifactory/api"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeServerApi(tt.server); got != tt.want {
+			t.Errorf("NormalizeServerApi(%q) = %q, want %q", tt.server, got, tt.want)
+		}
+	}
+}
+
+func TestConnectionConfigPrepare(t *testing.T) {
+	t.Setenv("ARTIFACTORY_TOKEN", "env-token")
+	t.Setenv("ARTIFACTORY_SERVER", "https://env.server.com/artifactory/")
+
+	config := ConnectionConfig{}
+	if errs := config.Prepare(); len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if config.ArtifactoryToken != "env-token" {
+		t.Errorf("token = %q, want value from ARTIFACTORY_TOKEN", config.ArtifactoryToken)
+	}
+	if config.ArtifactoryServer != "https://env.server.com/artifactory/api" {
+		t.Errorf("server = %q, want normalized value from ARTIFACTORY_SERVER", config.ArtifactoryServer)
+	}
+
+	config = ConnectionConfig{ArtifactoryToken: "config-token", ArtifactoryServer: "https://config.server.com/artifactory/api"}
+	config.Prepare()
+	if config.ArtifactoryToken != "config-token" || config.ArtifactoryServer != "https://config.server.com/artifactory/api" {
+		t.Errorf("explicit settings should win over the environment, got %+v", config)
+	}
+}
+
+func TestConnectionConfigPrepareMissing(t *testing.T) {
+	t.Setenv("ARTIFACTORY_TOKEN", "")
+	t.Setenv("ARTIFACTORY_SERVER", "")
+
+	config := ConnectionConfig{}
+	if errs := config.Prepare(); len(errs) != 2 {
+		t.Fatalf("expected an error for both the token and the server, got %v", errs)
+	}
+}
+
+func TestClientUrls(t *testing.T) {
+	c := New(&ConnectionConfig{ArtifactoryToken: "abc", ArtifactoryServer: "https://server.com:8081/artifactory/api/"})
+
+	if got, want := c.BaseUrl(), "https://server.com:8081/artifactory"; got != want {
+		t.Errorf("BaseUrl() = %q, want %q", got, want)
+	}
+	if got, want := c.StorageUrl("/repo/folder/file.ova"), "https://server.com:8081/artifactory/api/storage/repo/folder/file.ova"; got != want {
+		t.Errorf("StorageUrl() = %q, want %q", got, want)
+	}
+	if got, want := c.DownloadUrl("repo/folder/file.ova"), "https://server.com:8081/artifactory/repo/folder/file.ova"; got != want {
+		t.Errorf("DownloadUrl() = %q, want %q", got, want)
+	}
+
+	if got, want := c.StorageUrl("/repo/win 22/win 22#1.ova"), "https://server.com:8081/artifactory/api/storage/repo/win%2022/win%2022%231.ova"; got != want {
+		t.Errorf("StorageUrl() = %q, want %q", got, want)
+	}
+	if got, want := c.DownloadUrl("/repo/50%/a?b;c.ova"), "https://server.com:8081/artifactory/repo/50%25/a%3Fb%3Bc.ova"; got != want {
+		t.Errorf("DownloadUrl() = %q, want %q", got, want)
+	}
+
+	request, err := c.NewRequest(context.Background(), "GET", c.ApiUrl("/search/artifact?name=win22"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := request.Header.Get("Authorization"); got != "Bearer abc" {
+		t.Errorf("Authorization header = %q, want bearer token", got)
+	}
+	if got, want := request.URL.String(), "https://server.com:8081/artifactory/api/search/artifact?name=win22"; got != want {
+		t.Errorf("request URL = %q, want %q", got, want)
+	}
+}
+
+func TestRepoPathFromDownloadUrl(t *testing.T) {
+	c := New(&ConnectionConfig{ArtifactoryToken: "abc", ArtifactoryServer: "https://server.com/artifactory/api"})
+
+	got, err := c.RepoPathFromDownloadUrl("https://server.com/artifactory/images/Win%2022/Win%2022.ova")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "/images/Win 22/Win 22.ova"; got != want {
+		t.Errorf("RepoPathFromDownloadUrl() = %q, want %q", got, want)
+	}
+
+	got, err = c.RepoPathFromDownloadUrl("https://server.com/artifactory/api/storage/images/win22.ova")
+	if err != nil || got != "/images/win22.ova" {
+		t.Errorf("RepoPathFromDownloadUrl() of a storage URI = %q, %v", got, err)
+	}
+
+	if _, err := c.RepoPathFromDownloadUrl("https://other.com/artifactory/images/win22.ova"); err == nil {
+		t.Error("expected an error for a URI on another server")
+	}
+}
@@ -0,0 +1,39 @@
+package client
+
+import (
+	"errors"
+	"os"
+)
+
+// ConnectionConfig holds the Artifactory connection settings shared by every datasource and post-processor.
+// It is squashed into each component's Config, so the HCL attribute names stay the same everywhere.
+type ConnectionConfig struct {
+	// Will use the ARTIFACTORY_TOKEN environment variable if left blank
+	ArtifactoryToken string `mapstructure:"artifactory_token" required:"true"`
+	// Will use the ARTIFACTORY_SERVER environment variable if left blank
+	ArtifactoryServer string `mapstructure:"artifactory_server" required:"true"`
+}
+
+// Prepare fills in any blank connection settings from the environment and normalizes the server address.
+// Every problem found is returned so the caller can report them together.
+func (c *ConnectionConfig) Prepare() []error {
+	var errs []error
+
+	if c.ArtifactoryToken == "" {
+		c.ArtifactoryToken = os.Getenv("ARTIFACTORY_TOKEN")
+	}
+	if c.ArtifactoryToken == "" {
+		errs = append(errs, errors.New("Please provide an Artifactory Identity Token with 'artifactory_token' or the ARTIFACTORY_TOKEN environment variable."))
+	}
+
+	if c.ArtifactoryServer == "" {
+		c.ArtifactoryServer = os.Getenv("ARTIFACTORY_SERVER")
+	}
+	if c.ArtifactoryServer == "" {
+		errs = append(errs, errors.New("Please provide the URL to the Artifactory server with 'artifactory_server' or the ARTIFACTORY_SERVER environment variable (ex: https://server.com:8081/artifactory/api)."))
+	} else {
+		c.ArtifactoryServer = NormalizeServerApi(c.ArtifactoryServer)
+	}
+
+	return errs
+}
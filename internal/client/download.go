@@ -0,0 +1,108 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ProgressTracker follows the progress of a download by wrapping its body. packersdk.Ui is one, drawing a
+// progress bar in the Packer UI.
+type ProgressTracker interface {
+	TrackProgress(src string, currentSize, totalSize int64, stream io.ReadCloser) io.ReadCloser
+}
+
+// Download saves the file at the /repo/folder/file path to destPath, creating its folder if needed. If destPath
+// already holds the start of the file (ex: from an interrupted download), only the rest is requested with a Range
+// request; the whole file is downloaded again if the server doesn't support them, or answers from another byte.
+// Progress is reported to the tracker, if there is one. Returns the number of bytes written. Unlike the
+// artifactory-go-sdk downloads, any number of these can run at once.
+func (c *Client) Download(ctx context.Context, repoPath, destPath string, progress ProgressTracker) (int64, error) {
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return 0, fmt.Errorf("Unable to create the folder for %s: %s", destPath, err)
+	}
+	var offset int64
+	if info, err := os.Stat(destPath); err == nil && info.Mode().IsRegular() {
+		offset = info.Size()
+	}
+
+	url := c.DownloadUrl(repoPath)
+	response, err := c.get(ctx, url, offset)
+	if err != nil {
+		return 0, fmt.Errorf("Unable to download %s: %s", repoPath, err)
+	}
+	if offset > 0 && response.StatusCode == http.StatusPartialContent && !resumesAt(response, offset) {
+		// Appending would corrupt the file, so the whole file is requested again rather than failing the attempt
+		log.Printf("The server answered the request to resume %s at byte %d with the range %q; starting over",
+			repoPath, offset, response.Header.Get("Content-Range"))
+		response.Body.Close()
+		offset = 0
+		if response, err = c.get(ctx, url, 0); err != nil {
+			return 0, fmt.Errorf("Unable to download %s: %s", repoPath, err)
+		}
+	}
+	defer response.Body.Close()
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	resumeAt := int64(0)
+	switch {
+	case offset > 0 && response.StatusCode == http.StatusPartialContent:
+		log.Printf("Resuming the download of %s at byte %d", repoPath, offset)
+		flags = os.O_WRONLY | os.O_APPEND
+		resumeAt = offset
+	case offset > 0 && response.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		// Nothing is left to download; the checksum check decides whether what's there is the file
+		return 0, nil
+	case response.StatusCode == http.StatusOK:
+		if offset > 0 {
+			log.Printf("The server sent all of %s instead of resuming at byte %d; starting over", repoPath, offset)
+		}
+	default:
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return 0, fmt.Errorf("GET %s returned status %d: %s", url, response.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	file, err := os.OpenFile(destPath, flags, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("Unable to create %s: %s", destPath, err)
+	}
+	var body io.ReadCloser = response.Body
+	if progress != nil {
+		total := response.ContentLength
+		if total >= 0 {
+			total += resumeAt
+		}
+		body = progress.TrackProgress(repoPath, resumeAt, total, response.Body)
+		defer body.Close()
+	}
+	written, err := io.Copy(file, body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return written, fmt.Errorf("Unable to download %s to %s: %s", repoPath, destPath, err)
+	}
+	return written, nil
+}
+
+// get requests the file at url, from byte offset on when it is above 0.
+func (c *Client) get(ctx context.Context, url string, offset int64) (*http.Response, error) {
+	request, err := c.NewRequest(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if offset > 0 {
+		request.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	return c.Do(request)
+}
+
+// resumesAt reports whether a 206 response starts at byte offset, ex: Content-Range: bytes 1024-4095/4096.
+func resumesAt(response *http.Response, offset int64) bool {
+	return strings.HasPrefix(response.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", offset))
+}
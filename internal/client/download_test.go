@@ -0,0 +1,144 @@
+package client
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"packer-plugin-artifactory/internal/testutil/fakeartifactory"
+)
+
+func TestDownload(t *testing.T) {
+	server := fakeartifactory.New(t)
+	server.AddArtifact("/generic/drivers/nic.zip", []byte("driver bundle"), nil)
+
+	c := New(&ConnectionConfig{ArtifactoryToken: fakeartifactory.Token, ArtifactoryServer: server.ApiUrl()})
+	dest := filepath.Join(t.TempDir(), "nested", "nic.zip")
+
+	written, err := c.Download(context.Background(), "/generic/drivers/nic.zip", dest, nil)
+	if err != nil {
+		t.Fatalf("Download() error = %s", err)
+	}
+	got, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "driver bundle" || written != int64(len(got)) {
+		t.Errorf("Download() wrote %d bytes: %q", written, got)
+	}
+
+	_, err = c.Download(context.Background(), "/generic/drivers/missing.zip", filepath.Join(t.TempDir(), "missing.zip"), nil)
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Download() of a missing file error = %v, want a 404", err)
+	}
+}
+
+func TestDownload_MisalignedRange(t *testing.T) {
+	server := fakeartifactory.New(t)
+	server.AddArtifact("/images/win22/win22.ova", []byte("0123456789"), nil)
+	c := New(&ConnectionConfig{ArtifactoryToken: fakeartifactory.Token, ArtifactoryServer: server.ApiUrl()})
+	dest := filepath.Join(t.TempDir(), "win22.ova")
+	if err := os.WriteFile(dest, []byte("01234"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The server answers the resume from byte 0, so the download starts over instead of failing
+	server.MisalignRanges(1)
+	written, err := c.Download(context.Background(), "/images/win22/win22.ova", dest, nil)
+	if err != nil {
+		t.Fatalf("Download() error = %s", err)
+	}
+	if got, _ := os.ReadFile(dest); string(got) != "0123456789" || written != 10 {
+		t.Errorf("Download() wrote %d bytes: %q", written, got)
+	}
+	if got := server.Ranges(); len(got) != 1 || got[0] != "bytes=5-" {
+		t.Errorf("Range requests = %v, want only the first one to have a range", got)
+	}
+}
+
+func TestDownload_SpecialCharacters(t *testing.T) {
+	server := fakeartifactory.New(t)
+	repoPath := "/images/win 22/win 22#1.ova"
+	item := server.AddArtifact(repoPath, []byte("win22"), nil)
+	c := New(&ConnectionConfig{ArtifactoryToken: fakeartifactory.Token, ArtifactoryServer: server.ApiUrl()})
+	ctx := context.Background()
+
+	info, err := c.StatFile(ctx, repoPath)
+	if err != nil || info.Sha256 != item.Sha256() {
+		t.Fatalf("StatFile() = %+v, %v", info, err)
+	}
+	if _, err := c.Download(ctx, repoPath, filepath.Join(t.TempDir(), "win22.ova"), nil); err != nil {
+		t.Fatalf("Download() error = %s", err)
+	}
+	for _, uri := range []string{c.DownloadUrl(repoPath), c.StorageUrl(repoPath)} {
+		if got, err := c.RepoPathFromDownloadUrl(uri); err != nil || got != repoPath {
+			t.Errorf("RepoPathFromDownloadUrl(%s) = %q, %v", uri, got, err)
+		}
+	}
+}
+
+// recordingTracker remembers what it was asked to track and how much was read through it.
+type recordingTracker struct {
+	src                    string
+	currentSize, totalSize int64
+	read                   int64
+	closed                 bool
+}
+
+func (r *recordingTracker) TrackProgress(src string, currentSize, totalSize int64, stream io.ReadCloser) io.ReadCloser {
+	r.src, r.currentSize, r.totalSize = src, currentSize, totalSize
+	return r.wrap(stream)
+}
+
+func (r *recordingTracker) wrap(stream io.ReadCloser) io.ReadCloser {
+	return struct {
+		io.Reader
+		io.Closer
+	}{
+		Reader: readerFunc(func(p []byte) (int, error) {
+			n, err := stream.Read(p)
+			r.read += int64(n)
+			return n, err
+		}),
+		Closer: closerFunc(func() error {
+			r.closed = true
+			return stream.Close()
+		}),
+	}
+}
+
+type readerFunc func([]byte) (int, error)
+
+func (f readerFunc) Read(p []byte) (int, error) { return f(p) }
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error { return f() }
+
+func TestDownload_Progress(t *testing.T) {
+	server := fakeartifactory.New(t)
+	server.AddArtifact("/images/win22/win22.ova", []byte("0123456789"), nil)
+	c := New(&ConnectionConfig{ArtifactoryToken: fakeartifactory.Token, ArtifactoryServer: server.ApiUrl()})
+	dest := filepath.Join(t.TempDir(), "win22.ova")
+
+	tracker := &recordingTracker{}
+	if _, err := c.Download(context.Background(), "/images/win22/win22.ova", dest, tracker); err != nil {
+		t.Fatalf("Download() error = %s", err)
+	}
+	if tracker.src != "/images/win22/win22.ova" || tracker.currentSize != 0 || tracker.totalSize != 10 || tracker.read != 10 || !tracker.closed {
+		t.Errorf("tracked %+v", tracker)
+	}
+
+	// Resuming starts the progress where the file left off
+	os.WriteFile(dest, []byte("0123"), 0644)
+	tracker = &recordingTracker{}
+	if _, err := c.Download(context.Background(), "/images/win22/win22.ova", dest, tracker); err != nil {
+		t.Fatalf("Download() error = %s", err)
+	}
+	if tracker.currentSize != 4 || tracker.totalSize != 10 || tracker.read != 6 {
+		t.Errorf("tracked %+v after resuming", tracker)
+	}
+}
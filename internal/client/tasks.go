@@ -0,0 +1,38 @@
+package client
+
+import (
+	"sync"
+
+	"github.com/raynaluzier/artifactory-go-sdk/common"
+	"github.com/raynaluzier/artifactory-go-sdk/tasks"
+)
+
+// The artifactory-go-sdk keeps the server, token and output directory in package level variables,
+// so only one call may be in flight through it at a time.
+var sdkMu sync.Mutex
+
+// UploadArtifacts uploads the OVA, OVF, or VMTX image files found in the source directory.
+func (c *Client) UploadArtifacts(imageType, imageName, sourceDir, targetDir string) string {
+	sdkMu.Lock()
+	defer sdkMu.Unlock()
+	return tasks.UploadArtifacts(c.ServerApi, c.token, imageType, imageName, sourceDir, targetDir)
+}
+
+// UploadGeneralArtifact uploads a single file from the source path to the /repo/folder path.
+func (c *Client) UploadGeneralArtifact(sourcePath, artifPath, fileName string) (string, error) {
+	sdkMu.Lock()
+	defer sdkMu.Unlock()
+	return tasks.UploadGeneralArtifact(c.ServerApi, c.token, sourcePath, artifPath, fileName)
+}
+
+// SetProps assigns the 'key=value' properties to the artifact.
+func (c *Client) SetProps(artifactUri string, kvProps []string) (string, error) {
+	sdkMu.Lock()
+	defer sdkMu.Unlock()
+	return tasks.SetProps(c.ServerApi, c.token, artifactUri, kvProps)
+}
+
+// ParseArtifUriForPath returns the /repo/folder/ path an existing artifact URI lives under.
+func (c *Client) ParseArtifUriForPath(artifactUri string) string {
+	return common.ParseArtifUriForPath(c.ServerApi, artifactUri)
+}
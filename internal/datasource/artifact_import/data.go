@@ -2,24 +2,28 @@
 package artifactImport
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
 	"os"
-	"strings"
+
+	"packer-plugin-artifactory/internal/client"
+	"packer-plugin-artifactory/internal/download"
 
 	"github.com/hashicorp/hcl/v2/hcldec"
 	"github.com/hashicorp/packer-plugin-sdk/hcl2helper"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	"github.com/hashicorp/packer-plugin-sdk/template/config"
 	artifCommon "github.com/raynaluzier/artifactory-go-sdk/common"
-	artifTasks "github.com/raynaluzier/artifactory-go-sdk/tasks"
 	vsTasks "github.com/raynaluzier/vsphere-go-sdk/tasks"
 	"github.com/zclconf/go-cty/cty"
 )
 
 // --> If making changes to this section, make sure the hcl2spec gets updated as well!
 type Config struct {
-	ArtifactoryToken       string `mapstructure:"artifactory_token" required:"true"`
-	ArtifactoryServer      string `mapstructure:"artifactory_server" required:"true"`
-	
+	client.ConnectionConfig `mapstructure:",squash"`
+
 	VcenterServer			string `mapstructure:"vcenter_server" required:"true"`
 	VcenterUser				string `mapstructure:"vcenter_user" required:"true"`
 	VcenterPassword			string `mapstructure:"vcenter_password" required:"true"`
@@ -38,6 +42,9 @@ type Config struct {
 	// Convert and import to vCenter without first downloading image (i.e. image was already downloaded previously)
 	// Defaults to false
 	ImportNoDownload		bool   `mapstructure:"import_no_download" required:"false"`
+	// Leave image files already in the output directory that match Artifactory's size and checksum
+	// Defaults to false
+	SkipExisting			bool   `mapstructure:"skip_existing" required:"false"`
 	SourceImagePath			string `mapstructure:"source_path" required:"false"` // required if bool is true
 }
 
@@ -46,7 +53,11 @@ type Datasource struct {
 }
 
 // --> If making changes to this section, make sure the hcl2spec gets updated as well!
-type DatasourceOutput struct {}
+type DatasourceOutput struct {
+	// Image files not downloaded because they already matched Artifactory ('skip_existing')
+	FilesSkipped int   `mapstructure:"files_skipped"`
+	BytesSkipped int64 `mapstructure:"bytes_skipped"`
+}
 
 func (d *Datasource) ConfigSpec() hcldec.ObjectSpec { 
 	return d.config.FlatMapstructure().HCL2Spec() 
@@ -58,59 +69,48 @@ func (d *Datasource) Configure(raws ...interface{}) error {
 		return err
 	}
 
-	if d.config.ArtifactoryToken == "" {
-		token := os.Getenv("ARTIFACTORY_TOKEN")
-		if token == "" {
-			log.Fatal("---> Please provide an Artifactory Identity Token.")
-		}
-	}
-	
-	if d.config.ArtifactoryServer == "" {
-		server := os.Getenv("ARTIFACTORY_SERVER")
-		if server == "" {
-			log.Fatal("---> Please provide the URL to the Artifactory server (ex: https://server.com:8081/artifactory/api).")
-		}
-	}
+	var errs *packersdk.MultiError
+	errs = packersdk.MultiErrorAppend(errs, d.config.ConnectionConfig.Prepare()...)
 
 	if d.config.VcenterServer == "" {
 		vcServer := os.Getenv("VCENTER_SERVER")
 		if vcServer == "" {
-			log.Fatal("---> Missing the target vCenter Server name.")
+			errs = packersdk.MultiErrorAppend(errs, errors.New("Missing the target vCenter Server name ('vcenter_server' or VCENTER_SERVER)."))
 		}
 	}
 
 	if d.config.VcenterUser == "" {
 		vcUser := os.Getenv("VCENTER_USER")
 		if vcUser == "" {
-			log.Fatal("---> Missing the vCenter Server username. This is required for authentication.")
+			errs = packersdk.MultiErrorAppend(errs, errors.New("Missing the vCenter Server username ('vcenter_user' or VCENTER_USER). This is required for authentication."))
 		}
 	}
 
 	if d.config.VcenterPassword == "" {
 		vcPass := os.Getenv("VCENTER_PASSWORD")
 		if vcPass == "" {
-			log.Fatal("---> Missing the vCenter Server password. This is required for authentication.")
+			errs = packersdk.MultiErrorAppend(errs, errors.New("Missing the vCenter Server password ('vcenter_password' or VCENTER_PASSWORD). This is required for authentication."))
 		}
 	}
 
 	if d.config.VcenterDatacenter == "" {
 		dcName := os.Getenv("VCENTER_DATACENTER")
 		if dcName == "" {
-			log.Fatal("---> Missing the target vCenter datacenter name.")
+			errs = packersdk.MultiErrorAppend(errs, errors.New("Missing the target vCenter datacenter name ('datacenter_name' or VCENTER_DATACENTER)."))
 		}
 	}
 
 	if d.config.VcenterDatastore == "" {
 		dsName := os.Getenv("VCENTER_DATASTORE")
 		if dsName == "" {
-			log.Fatal("---> Missing the target vCenter datastore name.")
+			errs = packersdk.MultiErrorAppend(errs, errors.New("Missing the target vCenter datastore name ('datastore_name' or VCENTER_DATASTORE)."))
 		}
 	}
 
 	if d.config.VcenterCluster == "" {
 		clusterName := os.Getenv("VCENTER_CLUSTER")
 		if clusterName == "" {
-			log.Fatal("---> Missing the target vCenter cluster.")
+			errs = packersdk.MultiErrorAppend(errs, errors.New("Missing the target vCenter cluster ('cluster_name' or VCENTER_CLUSTER)."))
 		}
 	}
 
@@ -135,22 +135,25 @@ func (d *Datasource) Configure(raws ...interface{}) error {
 	if d.config.OutputDir == "" && d.config.ImportNoDownload == false {
 		outputDir := os.Getenv("OUTPUTDIR")
 		if outputDir == "" {
-			log.Println("---> No output directory was provided.")
-			log.Fatal("Please provide the directory path to an accessible datastore.")
+			errs = packersdk.MultiErrorAppend(errs, errors.New("No output directory was provided. Please provide the directory path to an accessible datastore with 'output_dir' or OUTPUTDIR."))
 		}
 	}
 
 	if d.config.DownloadUri == "" && d.config.ImportNoDownload == false {
-		log.Println("---> No download URI for the artifact was provided. This is required if the artifact should be downloaded before importing into vCenter.")
-		log.Fatal("If the image does not need to be downloaded first, please set 'import_no_download' to TRUE, and provide full file path to the source directory where the image file (OVA, OVF, or VMTX) is located.")
+		errs = packersdk.MultiErrorAppend(errs, errors.New("No 'download_uri' for the artifact was provided. This is required if the artifact should be downloaded before importing into vCenter. "+
+			"If the image does not need to be downloaded first, please set 'import_no_download' to TRUE, and provide full file path to the source directory where the image file (OVA, OVF, or VMTX) is located."))
 	}
 
 	if d.config.ImportNoDownload == true {
 		if d.config.SourceImagePath == "" {
-			log.Println("---> The 'import_no_download' flag is set to TRUE.")
-			log.Fatal("The 'source_path' to the full path for the image file (OVA, OVF, or VMTX) is required. Ex: '/lab/win22/win22.ova' If using a Windows path, ensure it is properly escaped with double-backslashes.")
+			errs = packersdk.MultiErrorAppend(errs, errors.New("The 'import_no_download' flag is set to TRUE, so 'source_path' to the full path for the image file (OVA, OVF, or VMTX) is required. "+
+				"Ex: '/lab/win22/win22.ova' If using a Windows path, ensure it is properly escaped with double-backslashes."))
 		}
 	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
 	return nil
 }
 
@@ -159,21 +162,11 @@ func (d *Datasource) OutputSpec() hcldec.ObjectSpec {
 }
 
 func (d *Datasource) Execute() (cty.Value, error) {
-	var downloadUri, sourcePath, importResult, token, serverApi, outputDir string
+	var downloadUri, sourcePath, importResult, outputDir string
 	var vcServer, vcUser, vcPass, dcName, dsName, clusterName, resPoolName, folderName, dsImagePath string
 
 	// Artifactory related
-	if d.config.ArtifactoryToken == "" {
-		token = os.Getenv("ARTIFACTORY_TOKEN")
-	} else {
-		token = d.config.ArtifactoryToken
-	}
-	
-	if d.config.ArtifactoryServer == "" {
-		serverApi = os.Getenv("ARTIFACTORY_SERVER")
-	} else {
-		serverApi = d.config.ArtifactoryServer
-	}
+	artifClient := client.New(&d.config.ConnectionConfig)
 
 	// vCenter Related
 	if d.config.VcenterServer == "" {
@@ -242,6 +235,8 @@ func (d *Datasource) Execute() (cty.Value, error) {
 		dsImagePath = ""
 	}
 
+	output := DatasourceOutput{}
+
 	var importNoDownload = false  // default; whether we will convert and import the image into vCenter without downloading first (i.e. image already downloaded previously)
 	if d.config.ImportNoDownload == true {
 		importNoDownload = true
@@ -256,7 +251,7 @@ func (d *Datasource) Execute() (cty.Value, error) {
 
 	folderId, resPoolId, err := vsTasks.GetResourceIds(vcUser, vcPass, vcServer, dcName, folderName, resPoolName, clusterName)
 	if err != nil {
-		log.Fatal("Error getting folder and resource pool IDs")
+		return cty.NullVal(cty.EmptyObject), fmt.Errorf("Error getting folder and resource pool IDs: %s", err)
 	}
 	
 	// If we are downloading first, parse for needed details, then proceed with download, conversion, import, and templating 
@@ -264,22 +259,30 @@ func (d *Datasource) Execute() (cty.Value, error) {
 		imageFileName := artifCommon.ParseUriForFilename(downloadUri)
 		imageName     := artifCommon.ParseFilenameForImageName(imageFileName)
 
-		// Download Artifacts
-		downloadResult := artifTasks.DownloadArtifacts(serverApi, token, downloadUri, outputDir)
-		log.Println("Download Result: " + downloadResult)
-		
-		var missingInputsMsg  = "Missing required inputs"
-		var downloadFailedMsg = "File download failed"
-
-		// If the download result doesn't contain one of these msgs, proceed with import
-		if !strings.Contains(downloadResult, missingInputsMsg) || !strings.Contains(downloadResult, downloadFailedMsg) {
-			log.Println("Image download completed successfully.")
-			log.Println("Checking image type and converting if necessary. This may time some time...")
-
-			importResult = vsTasks.ConvertImportFromDownload(vcUser, vcPass, vcServer, outputDir, downloadUri, dcName, dsName, dsImagePath, imageName, folderId, resPoolId)
-		} else {
-			log.Fatal("Error: Failures occurred during image download.")
+		// Download the image and its associated files, checking each against its checksum in Artifactory
+		ctx := context.TODO()
+		repoPath, err := artifClient.RepoPathFromDownloadUrl(downloadUri)
+		if err != nil {
+			return cty.NullVal(cty.EmptyObject), fmt.Errorf("Failures occurred during image download: %s", err)
+		}
+		files, err := download.ImageFiles(ctx, artifClient, repoPath, outputDir)
+		if err != nil {
+			return cty.NullVal(cty.EmptyObject), fmt.Errorf("Failures occurred during image download: %s", err)
+		}
+		// Datasources aren't given a Packer UI, so each download's progress is logged
+		results := download.All(ctx, artifClient, files, download.Options{SkipExisting: d.config.SkipExisting})
+		if err := download.Error(results); err != nil {
+			return cty.NullVal(cty.EmptyObject), fmt.Errorf("Failures occurred during image download: %s", err)
 		}
+		output.FilesSkipped, output.BytesSkipped = download.Skipped(results)
+		if output.FilesSkipped > 0 {
+			log.Printf("Skipped %d image file(s) that were already downloaded, avoiding %d bytes", output.FilesSkipped, output.BytesSkipped)
+		}
+
+		log.Println("Image download completed successfully.")
+		log.Println("Checking image type and converting if necessary. This may time some time...")
+
+		importResult = vsTasks.ConvertImportFromDownload(vcUser, vcPass, vcServer, outputDir, downloadUri, dcName, dsName, dsImagePath, imageName, folderId, resPoolId)
 	} else {   // no download flag is true
 		log.Println("Checking image type and converting if necessary. This may time some time...")
 		importResult = vsTasks.ConvertImportNoDownload(vcUser, vcPass, vcServer, dcName, dsName, sourcePath, dsImagePath, folderId, resPoolId)
@@ -289,10 +292,8 @@ func (d *Datasource) Execute() (cty.Value, error) {
 	if importResult == "Success" {
 		log.Println("Process completed successfully.")
 	} else if importResult == "Failed" || importResult == "" {
-		log.Fatal("Process did not complete successfully.")
+		return cty.NullVal(cty.EmptyObject), errors.New("Image import did not complete successfully.")
 	}
-	
-	output := DatasourceOutput{}
 
 	return hcl2helper.HCL2ValueFromConfig(output, d.OutputSpec()), nil
 }
\ No newline at end of file
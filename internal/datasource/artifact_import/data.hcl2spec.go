@@ -24,6 +24,7 @@ type FlatConfig struct {
 	DownloadUri         *string `mapstructure:"download_uri" required:"false" cty:"download_uri" hcl:"download_uri"`
 	DsImagePath         *string `mapstructure:"ds_image_path" required:"false" cty:"ds_image_path" hcl:"ds_image_path"`
 	ImportNoDownload    *bool   `mapstructure:"import_no_download" required:"false" cty:"import_no_download" hcl:"import_no_download"`
+	SkipExisting        *bool   `mapstructure:"skip_existing" required:"false" cty:"skip_existing" hcl:"skip_existing"`
 	SourceImagePath     *string `mapstructure:"source_path" required:"false" cty:"source_path" hcl:"source_path"`
 }
 
@@ -53,6 +54,7 @@ func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
 		"download_uri":       &hcldec.AttrSpec{Name: "download_uri", Type: cty.String, Required: false},
 		"ds_image_path":      &hcldec.AttrSpec{Name: "ds_image_path", Type: cty.String, Required: false},
 		"import_no_download": &hcldec.AttrSpec{Name: "import_no_download", Type: cty.Bool, Required: false},
+		"skip_existing":      &hcldec.AttrSpec{Name: "skip_existing", Type: cty.Bool, Required: false},
 		"source_path":        &hcldec.AttrSpec{Name: "source_path", Type: cty.String, Required: false},
 	}
 	return s
@@ -61,6 +63,8 @@ func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
 // FlatDatasourceOutput is an auto-generated flat version of DatasourceOutput.
 // Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
 type FlatDatasourceOutput struct {
+	FilesSkipped *int   `mapstructure:"files_skipped" cty:"files_skipped" hcl:"files_skipped"`
+	BytesSkipped *int64 `mapstructure:"bytes_skipped" cty:"bytes_skipped" hcl:"bytes_skipped"`
 }
 
 // FlatMapstructure returns a new FlatDatasourceOutput.
@@ -74,6 +78,9 @@ func (*DatasourceOutput) FlatMapstructure() interface{ HCL2Spec() map[string]hcl
 // This spec is used by HCL to read the fields of DatasourceOutput.
 // The decoded values from this spec will then be applied to a FlatDatasourceOutput.
 func (*FlatDatasourceOutput) HCL2Spec() map[string]hcldec.Spec {
-	s := map[string]hcldec.Spec{}
+	s := map[string]hcldec.Spec{
+		"files_skipped": &hcldec.AttrSpec{Name: "files_skipped", Type: cty.Number, Required: false},
+		"bytes_skipped": &hcldec.AttrSpec{Name: "bytes_skipped", Type: cty.Number, Required: false},
+	}
 	return s
 }
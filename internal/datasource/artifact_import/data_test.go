@@ -0,0 +1,121 @@
+package artifactImport
+
+import (
+	"strings"
+	"testing"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestDatasourceConfigure_ReportsAllErrors(t *testing.T) {
+	for _, env := range []string{"ARTIFACTORY_TOKEN", "ARTIFACTORY_SERVER", "VCENTER_SERVER", "VCENTER_USER", "VCENTER_PASSWORD",
+		"VCENTER_DATACENTER", "VCENTER_DATASTORE", "VCENTER_CLUSTER", "VCENTER_FOLDER", "VCENTER_RESOURCE_POOL", "OUTPUTDIR"} {
+		t.Setenv(env, "")
+	}
+
+	d := &Datasource{}
+	err := d.Configure(map[string]interface{}{
+		"import_no_download": true,
+	})
+	if err == nil {
+		t.Fatal("expected an error for the missing settings")
+	}
+
+	multiErr, ok := err.(*packersdk.MultiError)
+	if !ok {
+		t.Fatalf("expected a *packer.MultiError, got %T", err)
+	}
+	// token, server, 6 vCenter settings, and source_path
+	if len(multiErr.Errors) != 9 {
+		t.Errorf("expected 9 errors, got %d: %s", len(multiErr.Errors), err)
+	}
+	if !strings.Contains(err.Error(), "source_path") {
+		t.Errorf("expected the missing 'source_path' to be reported, got: %s", err)
+	}
+}
+
+func TestDatasourceConfigure_Valid(t *testing.T) {
+	d := &Datasource{}
+	err := d.Configure(map[string]interface{}{
+		"artifactory_token":  "token",
+		"artifactory_server": "https://server.com/artifactory/api",
+		"vcenter_server":     "vc01.domain.com",
+		"vcenter_user":       "user",
+		"vcenter_password":   "pass",
+		"datacenter_name":    "dc",
+		"datastore_name":     "ds",
+		"cluster_name":       "cluster",
+		"output_dir":         "/lab/",
+		"download_uri":       "https://server.com/artifactory/repo/win22/win22.ova",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func TestDatasourceConfigure_DownloadSettings(t *testing.T) {
+	t.Setenv("OUTPUTDIR", "")
+
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		wantErr []string
+	}{
+		{
+			name:    "download without output_dir or download_uri",
+			config:  map[string]interface{}{},
+			wantErr: []string{"output_dir", "download_uri"},
+		},
+		{
+			name:    "download without download_uri",
+			config:  map[string]interface{}{"output_dir": "/lab/"},
+			wantErr: []string{"download_uri"},
+		},
+		{
+			name:   "import without download",
+			config: map[string]interface{}{"import_no_download": true, "source_path": "/lab/win22/win22.ova"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := map[string]interface{}{
+				"artifactory_token":  "token",
+				"artifactory_server": "https://server.com/artifactory/api",
+				"vcenter_server":     "vc01.domain.com",
+				"vcenter_user":       "user",
+				"vcenter_password":   "pass",
+				"datacenter_name":    "dc",
+				"datastore_name":     "ds",
+				"cluster_name":       "cluster",
+			}
+			for key, value := range tt.config {
+				raw[key] = value
+			}
+
+			d := &Datasource{}
+			err := d.Configure(raw)
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			multiErr, ok := err.(*packersdk.MultiError)
+			if !ok {
+				t.Fatalf("expected a *packer.MultiError, got %T", err)
+			}
+			if len(multiErr.Errors) != len(tt.wantErr) {
+				t.Errorf("expected %d errors, got %d: %s", len(tt.wantErr), len(multiErr.Errors), err)
+			}
+			for _, field := range tt.wantErr {
+				if !strings.Contains(err.Error(), field) {
+					t.Errorf("expected %q to be reported, got: %s", field, err)
+				}
+			}
+		})
+	}
+}
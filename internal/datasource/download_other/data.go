@@ -1,25 +1,51 @@
-//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config,DatasourceOutput,FileOutput
 package artifactDownloadOther
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"packer-plugin-artifactory/internal/archive"
+	"packer-plugin-artifactory/internal/client"
+	"packer-plugin-artifactory/internal/download"
 
 	"github.com/hashicorp/hcl/v2/hcldec"
 	"github.com/hashicorp/packer-plugin-sdk/hcl2helper"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	"github.com/hashicorp/packer-plugin-sdk/template/config"
-	"github.com/raynaluzier/artifactory-go-sdk/common"
-	"github.com/raynaluzier/artifactory-go-sdk/tasks"
 	"github.com/zclconf/go-cty/cty"
 )
 
 // --> If making changes to this section, make sure the hcl2spec gets updated as well!
 type Config struct {
-	ArtifactoryToken       string `mapstructure:"artifactory_token" required:"true"`
-	ArtifactoryServer      string `mapstructure:"artifactory_server" required:"true"`
+	client.ConnectionConfig `mapstructure:",squash"`
+
 	OutputDir			   string `mapstructure:"output_dir" required:"true"`
-	ArtifactoryPath        string `mapstructure:"artifactory_path" required:"true"`
-	FileList               []string `mapstructure:"file_list" required:"true"`
+	// Required, unless 'filter' is used
+	ArtifactoryPath        string `mapstructure:"artifactory_path" required:"false"`
+	FileList               []string `mapstructure:"file_list" required:"false"`
+	// Instead of a file list, download the files in the folder that match these globs (all of them, if none)
+	Include                []string `mapstructure:"include" required:"false"`
+	Exclude                []string `mapstructure:"exclude" required:"false"`
+	// Include the files in every folder below 'artifactory_path'; the folder structure is kept in 'output_dir'
+	Recursive              bool `mapstructure:"recursive" required:"false"`
+	// Instead of a path, download the files with all of these properties (ex: bundle = "vmware-tools");
+	// 'artifactory_path', 'include', and 'exclude' narrow down what is found
+	Filter                 map[string]string `mapstructure:"filter" required:"false"`
+	// How many files to download at once; defaults to 4
+	Parallelism            int `mapstructure:"parallelism" required:"false"`
+	// Leave files already in the output directory that match Artifactory's size and checksum; defaults to false
+	SkipExisting           bool `mapstructure:"skip_existing" required:"false"`
+	// Unpack the zip, tar, tar.gz, and tar.zst files downloaded into 'extract_dir'; defaults to false
+	Extract                bool `mapstructure:"extract" required:"false"`
+	// Where archives are unpacked to; defaults to 'output_dir'
+	ExtractDir             string `mapstructure:"extract_dir" required:"false"`
 }
 
 type Datasource struct {
@@ -27,7 +53,31 @@ type Datasource struct {
 }
 
 // --> If making changes to this section, make sure the hcl2spec gets updated as well!
-type DatasourceOutput struct {}
+type DatasourceOutput struct {
+	// The local path of every file, keyed by its path relative to 'output_dir' with '/' separators (ex:
+	// nic/intel/e1000.inf; just the file name unless the folder structure is kept)
+	Files        map[string]string `mapstructure:"files"`
+	// Details of every file, in the same order as 'file_list', or sorted by Artifactory path otherwise
+	Downloads    []FileOutput `mapstructure:"downloads"`
+	// The local path of every file unpacked from the archives ('extract'), sorted
+	ExtractedFiles []string `mapstructure:"extracted_files"`
+	// Files not downloaded because they already matched Artifactory ('skip_existing')
+	FilesSkipped int   `mapstructure:"files_skipped"`
+	BytesSkipped int64 `mapstructure:"bytes_skipped"`
+}
+
+// --> If making changes to this section, make sure the hcl2spec gets updated as well!
+type FileOutput struct {
+	// Path relative to 'output_dir'; the key of this file in 'files'
+	Name        string `mapstructure:"name"`
+	LocalPath   string `mapstructure:"local_path"`
+	DownloadUri string `mapstructure:"download_uri"`
+	// Size in bytes
+	Size        int64  `mapstructure:"size"`
+	Sha256      string `mapstructure:"sha256"`
+	// Whether the file was already in 'output_dir' and left as it was ('skip_existing')
+	Skipped     bool   `mapstructure:"skipped"`
+}
 
 func (d *Datasource) ConfigSpec() hcldec.ObjectSpec { 
 	return d.config.FlatMapstructure().HCL2Spec() 
@@ -39,34 +89,68 @@ func (d *Datasource) Configure(raws ...interface{}) error {
 		return err
 	}
 
-	if d.config.ArtifactoryToken == "" {
-		token := os.Getenv("ARTIFACTORY_TOKEN")
-		if token == "" {
-			log.Fatal("---> Please provide an Artifactory Identity Token.")
+	var errs *packersdk.MultiError
+	errs = packersdk.MultiErrorAppend(errs, d.config.ConnectionConfig.Prepare()...)
+
+	if d.config.OutputDir == "" {
+		d.config.OutputDir = os.Getenv("OUTPUTDIR")
+	}
+	if d.config.OutputDir == "" {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("Please provide the path to the desired output directory for the files being downloaded with 'output_dir' or OUTPUTDIR. "+
+			"Path should include proper escape characters where necessary."))
+	}
+
+	bySearch := len(d.config.Filter) > 0
+	if d.config.ArtifactoryPath == "" && !bySearch {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("Please provide the repo path in Artifactory where the artifacts reside with 'artifactory_path' (ex: /repo/folder/), "+
+			"or the properties of the artifacts with 'filter'."))
+	}
+
+	listing := len(d.config.Include) > 0 || len(d.config.Exclude) > 0 || d.config.Recursive
+	switch {
+	case bySearch && (len(d.config.FileList) > 0 || d.config.Recursive):
+		errs = packersdk.MultiErrorAppend(errs, errors.New("'filter' can't be used with 'file_list' or 'recursive'; "+
+			"files are found in every folder below 'artifactory_path', and 'include' and 'exclude' narrow them down."))
+	case len(d.config.FileList) > 0 && listing:
+		errs = packersdk.MultiErrorAppend(errs, errors.New("Please use either 'file_list', or 'include', 'exclude', and 'recursive', but not both."))
+	case len(d.config.FileList) <= 0 && !listing && !bySearch:
+		errs = packersdk.MultiErrorAppend(errs, errors.New("Please provide a list of one or more filenames to be downloaded with 'file_list', "+
+			"patterns for the files to download with 'include', or their properties with 'filter'. "+
+			"Ex:  file_list = [\"file1.txt\", \"file2.txt\"] or include = [\"*.txt\"]"))
+	}
+	for key := range d.config.Filter {
+		if strings.TrimSpace(key) == "" {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("'filter' includes an empty property name."))
 		}
 	}
-	
-	if d.config.ArtifactoryServer == "" {
-		server := os.Getenv("ARTIFACTORY_SERVER")
-		if server == "" {
-			log.Fatal("---> Please provide the URL to the Artifactory server (ex: https://server.com:8081/artifactory/api).")
+	seen := map[string]bool{}
+	for _, file := range d.config.FileList {
+		if seen[file] {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("'file_list' includes %q more than once.", file))
+		}
+		seen[file] = true
+		if !filepath.IsLocal(filepath.FromSlash(file)) {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("'file_list' entry %q would be saved outside of 'output_dir'; "+
+				"entries are paths relative to 'artifactory_path'.", file))
 		}
 	}
+	errs = packersdk.MultiErrorAppend(errs, PrepareGlobs("include", d.config.Include)...)
+	errs = packersdk.MultiErrorAppend(errs, PrepareGlobs("exclude", d.config.Exclude)...)
 
-	if d.config.OutputDir == "" {
-		log.Println("---> Please provide the path to the desired output directory for the files being downloaded.")
-		log.Fatal("Path should include proper escape characters where necessary.")
+	if d.config.ExtractDir != "" && !d.config.Extract {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("'extract_dir' is only used when 'extract' is true."))
 	}
 
-	if d.config.ArtifactoryPath == "" {
-		log.Fatal("---> Please provide the repo path in Artifactory where the artifacts reside (ex: /repo/folder/).")
+	if d.config.Parallelism < 0 {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("'parallelism' must be 1 or more."))
 	}
-
-	if len(d.config.FileList) <= 0 {
-		log.Println("---> Please provide a list of one or more filenames to be downloaded.")
-		log.Fatal("Ex:  file_list = [\"file1.txt\", \"file2.txt\"]")
+	if d.config.Parallelism == 0 {
+		d.config.Parallelism = download.DefaultParallelism
 	}
 
+	if len(errs.Errors) > 0 {
+		return errs
+	}
 	return nil
 }
 
@@ -75,29 +159,13 @@ func (d *Datasource) OutputSpec() hcldec.ObjectSpec {
 }
 
 func (d *Datasource) Execute() (cty.Value, error) {
-	var serverApi, token, artifPath, outputDir, result string
+	var artifPath string
 	var fileList []string
-	var err error
 
-	// Environment related
-	if d.config.ArtifactoryToken == "" {
-		token = os.Getenv("ARTIFACTORY_TOKEN")
-	} else {
-		token = d.config.ArtifactoryToken
-	}
-	
-	if d.config.ArtifactoryServer == "" {
-		serverApi = os.Getenv("ARTIFACTORY_SERVER")
-	} else {
-		serverApi = d.config.ArtifactoryServer
-	}
+	artifClient := client.New(&d.config.ConnectionConfig)
 
 	// Artifact Related
-	if d.config.OutputDir == "" {
-		outputDir = os.Getenv("OUTPUTDIR")
-	} else {
-		outputDir = d.config.OutputDir
-	}
+	outputDir := d.config.OutputDir
 
 	if d.config.ArtifactoryPath != "" {
 		artifPath = d.config.ArtifactoryPath
@@ -107,30 +175,118 @@ func (d *Datasource) Execute() (cty.Value, error) {
 		fileList = d.config.FileList
 	}
 
-	log.Println(serverApi)
+	log.Println(artifClient.ServerApi)
 	log.Println(artifPath)
 
-	serverApi = common.FormatServerForDownloadUri(serverApi)
-	serverApi = common.TrimEndSlashUrl(serverApi)
-	downloadPath := serverApi + artifPath
-	downloadPath = common.CheckAddSlashToPath(downloadPath)
-	log.Println("Server API Reformed: " + serverApi)
+	downloadPath := strings.TrimSuffix(artifClient.DownloadUrl(artifPath), "/") + "/"
 	log.Println("Download Path: " + downloadPath)
 
-	for _, file := range fileList {
-		task := "Downloading: " + file
-		log.Println(task)
-		log.Println("Artifact Download URI should be: " + downloadPath + file)
-		result, err = tasks.DownloadGeneralArtifact(serverApi, token, outputDir, artifPath, file, task)
-		log.Println("Result of download: " + file + " - " + result)
+	ctx := context.Background()
+	var files []download.File
+	if len(fileList) > 0 {
+		for _, file := range fileList {
+			files = append(files, download.File{
+				FileInfo:  client.FileInfo{RepoPath: "/" + strings.Trim(artifPath, "/") + "/" + file},
+				LocalPath: filepath.Join(outputDir, file),
+			})
+		}
+	} else if len(d.config.Filter) > 0 {
+		var err error
+		files, err = d.searchFiles(ctx, artifClient, artifPath, outputDir)
+		if err != nil {
+			return cty.NullVal(cty.EmptyObject), err
+		}
+		log.Printf("Found %d file(s) to download with the properties %s", len(files), FilterString(d.config.Filter))
+	} else {
+		var err error
+		files, err = d.listFiles(ctx, artifClient, artifPath, outputDir)
+		if err != nil {
+			return cty.NullVal(cty.EmptyObject), err
+		}
+		log.Printf("Found %d file(s) to download in %s", len(files), artifPath)
 	}
 
-	if err != nil {
+	// Datasources aren't given a Packer UI, so each download's progress is logged
+	results := download.All(ctx, artifClient, files, download.Options{
+		Parallelism:  d.config.Parallelism,
+		SkipExisting: d.config.SkipExisting,
+	})
+	if err := download.Error(results); err != nil {
 		log.Println("There were errors downloading one or more files")
-		log.Println(err)
+		return cty.NullVal(cty.EmptyObject), err
 	}
 
-	output := DatasourceOutput{}
+	output, err := fileOutputs(artifClient, results, outputDir)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+	output.FilesSkipped, output.BytesSkipped = download.Skipped(results)
+	if d.config.Extract {
+		extractDir := d.config.ExtractDir
+		if extractDir == "" {
+			extractDir = outputDir
+		}
+		if output.ExtractedFiles, err = extractArchives(results, extractDir); err != nil {
+			return cty.NullVal(cty.EmptyObject), err
+		}
+	}
+	if output.FilesSkipped > 0 {
+		log.Printf("Skipped %d file(s) that were already downloaded, avoiding %d bytes", output.FilesSkipped, output.BytesSkipped)
+	}
 
 	return hcl2helper.HCL2ValueFromConfig(output, d.OutputSpec()), nil
-}
\ No newline at end of file
+}
+
+// fileOutputs describes the downloaded files. Artifactory may only have a sha1 for a file, so the sha256 is
+// worked out from the local copy in that case.
+func fileOutputs(artifClient *client.Client, results []download.Result, outputDir string) (DatasourceOutput, error) {
+	output := DatasourceOutput{Files: map[string]string{}}
+	for _, result := range results {
+		name, err := filepath.Rel(outputDir, result.LocalPath)
+		if err != nil {
+			return output, err
+		}
+		name = filepath.ToSlash(name)
+
+		sha256 := strings.ToLower(result.Sha256)
+		if sha256 == "" {
+			if sha256, err = download.LocalSha256(result.LocalPath); err != nil {
+				return output, err
+			}
+		}
+
+		output.Files[name] = result.LocalPath
+		output.Downloads = append(output.Downloads, FileOutput{
+			Name:        name,
+			LocalPath:   result.LocalPath,
+			DownloadUri: artifClient.DownloadUrl(result.RepoPath),
+			Size:        result.Size,
+			Sha256:      sha256,
+			Skipped:     result.Skipped,
+		})
+	}
+	return output, nil
+}
+
+// extractArchives unpacks every downloaded archive into extractDir and returns the extracted files.
+func extractArchives(results []download.Result, extractDir string) ([]string, error) {
+	extracted := []string{}
+	archives := 0
+	for _, result := range results {
+		if archive.Format(result.LocalPath) == "" {
+			continue
+		}
+		archives++
+		files, err := archive.Extract(result.LocalPath, extractDir)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("Extracted %d file(s) from %s to %s", len(files), result.LocalPath, extractDir)
+		extracted = append(extracted, files...)
+	}
+	if archives == 0 {
+		log.Println("[WARN] 'extract' is set, but none of the files downloaded are zip, tar, tar.gz, or tar.zst archives")
+	}
+	sort.Strings(extracted)
+	return extracted, nil
+}
@@ -10,11 +10,19 @@ import (
 // FlatConfig is an auto-generated flat version of Config.
 // Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
 type FlatConfig struct {
-	ArtifactoryToken  *string  `mapstructure:"artifactory_token" required:"true" cty:"artifactory_token" hcl:"artifactory_token"`
-	ArtifactoryServer *string  `mapstructure:"artifactory_server" required:"true" cty:"artifactory_server" hcl:"artifactory_server"`
-	OutputDir         *string  `mapstructure:"output_dir" required:"true" cty:"output_dir" hcl:"output_dir"`
-	ArtifactoryPath   *string  `mapstructure:"artifactory_path" required:"true" cty:"artifactory_path" hcl:"artifactory_path"`
-	FileList          []string `mapstructure:"file_list" required:"true" cty:"file_list" hcl:"file_list"`
+	ArtifactoryToken  *string           `mapstructure:"artifactory_token" required:"true" cty:"artifactory_token" hcl:"artifactory_token"`
+	ArtifactoryServer *string           `mapstructure:"artifactory_server" required:"true" cty:"artifactory_server" hcl:"artifactory_server"`
+	OutputDir         *string           `mapstructure:"output_dir" required:"true" cty:"output_dir" hcl:"output_dir"`
+	ArtifactoryPath   *string           `mapstructure:"artifactory_path" required:"false" cty:"artifactory_path" hcl:"artifactory_path"`
+	FileList          []string          `mapstructure:"file_list" required:"false" cty:"file_list" hcl:"file_list"`
+	Include           []string          `mapstructure:"include" required:"false" cty:"include" hcl:"include"`
+	Exclude           []string          `mapstructure:"exclude" required:"false" cty:"exclude" hcl:"exclude"`
+	Recursive         *bool             `mapstructure:"recursive" required:"false" cty:"recursive" hcl:"recursive"`
+	Filter            map[string]string `mapstructure:"filter" required:"false" cty:"filter" hcl:"filter"`
+	Parallelism       *int              `mapstructure:"parallelism" required:"false" cty:"parallelism" hcl:"parallelism"`
+	SkipExisting      *bool             `mapstructure:"skip_existing" required:"false" cty:"skip_existing" hcl:"skip_existing"`
+	Extract           *bool             `mapstructure:"extract" required:"false" cty:"extract" hcl:"extract"`
+	ExtractDir        *string           `mapstructure:"extract_dir" required:"false" cty:"extract_dir" hcl:"extract_dir"`
 }
 
 // FlatMapstructure returns a new FlatConfig.
@@ -34,6 +42,14 @@ func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
 		"output_dir":         &hcldec.AttrSpec{Name: "output_dir", Type: cty.String, Required: false},
 		"artifactory_path":   &hcldec.AttrSpec{Name: "artifactory_path", Type: cty.String, Required: false},
 		"file_list":          &hcldec.AttrSpec{Name: "file_list", Type: cty.List(cty.String), Required: false},
+		"include":            &hcldec.AttrSpec{Name: "include", Type: cty.List(cty.String), Required: false},
+		"exclude":            &hcldec.AttrSpec{Name: "exclude", Type: cty.List(cty.String), Required: false},
+		"recursive":          &hcldec.AttrSpec{Name: "recursive", Type: cty.Bool, Required: false},
+		"filter":             &hcldec.AttrSpec{Name: "filter", Type: cty.Map(cty.String), Required: false},
+		"parallelism":        &hcldec.AttrSpec{Name: "parallelism", Type: cty.Number, Required: false},
+		"skip_existing":      &hcldec.AttrSpec{Name: "skip_existing", Type: cty.Bool, Required: false},
+		"extract":            &hcldec.AttrSpec{Name: "extract", Type: cty.Bool, Required: false},
+		"extract_dir":        &hcldec.AttrSpec{Name: "extract_dir", Type: cty.String, Required: false},
 	}
 	return s
 }
@@ -41,6 +57,11 @@ func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
 // FlatDatasourceOutput is an auto-generated flat version of DatasourceOutput.
 // Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
 type FlatDatasourceOutput struct {
+	Files          map[string]string `mapstructure:"files" cty:"files" hcl:"files"`
+	Downloads      []FlatFileOutput  `mapstructure:"downloads" cty:"downloads" hcl:"downloads"`
+	ExtractedFiles []string          `mapstructure:"extracted_files" cty:"extracted_files" hcl:"extracted_files"`
+	FilesSkipped   *int              `mapstructure:"files_skipped" cty:"files_skipped" hcl:"files_skipped"`
+	BytesSkipped   *int64            `mapstructure:"bytes_skipped" cty:"bytes_skipped" hcl:"bytes_skipped"`
 }
 
 // FlatMapstructure returns a new FlatDatasourceOutput.
@@ -54,6 +75,45 @@ func (*DatasourceOutput) FlatMapstructure() interface{ HCL2Spec() map[string]hcl
 // This spec is used by HCL to read the fields of DatasourceOutput.
 // The decoded values from this spec will then be applied to a FlatDatasourceOutput.
 func (*FlatDatasourceOutput) HCL2Spec() map[string]hcldec.Spec {
-	s := map[string]hcldec.Spec{}
+	s := map[string]hcldec.Spec{
+		"files":           &hcldec.AttrSpec{Name: "files", Type: cty.Map(cty.String), Required: false},
+		"downloads":       &hcldec.BlockListSpec{TypeName: "downloads", Nested: hcldec.ObjectSpec((*FlatFileOutput)(nil).HCL2Spec())},
+		"extracted_files": &hcldec.AttrSpec{Name: "extracted_files", Type: cty.List(cty.String), Required: false},
+		"files_skipped":   &hcldec.AttrSpec{Name: "files_skipped", Type: cty.Number, Required: false},
+		"bytes_skipped":   &hcldec.AttrSpec{Name: "bytes_skipped", Type: cty.Number, Required: false},
+	}
+	return s
+}
+
+// FlatFileOutput is an auto-generated flat version of FileOutput.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatFileOutput struct {
+	Name        *string `mapstructure:"name" cty:"name" hcl:"name"`
+	LocalPath   *string `mapstructure:"local_path" cty:"local_path" hcl:"local_path"`
+	DownloadUri *string `mapstructure:"download_uri" cty:"download_uri" hcl:"download_uri"`
+	Size        *int64  `mapstructure:"size" cty:"size" hcl:"size"`
+	Sha256      *string `mapstructure:"sha256" cty:"sha256" hcl:"sha256"`
+	Skipped     *bool   `mapstructure:"skipped" cty:"skipped" hcl:"skipped"`
+}
+
+// FlatMapstructure returns a new FlatFileOutput.
+// FlatFileOutput is an auto-generated flat version of FileOutput.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*FileOutput) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatFileOutput)
+}
+
+// HCL2Spec returns the hcl spec of a FileOutput.
+// This spec is used by HCL to read the fields of FileOutput.
+// The decoded values from this spec will then be applied to a FlatFileOutput.
+func (*FlatFileOutput) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"name":         &hcldec.AttrSpec{Name: "name", Type: cty.String, Required: false},
+		"local_path":   &hcldec.AttrSpec{Name: "local_path", Type: cty.String, Required: false},
+		"download_uri": &hcldec.AttrSpec{Name: "download_uri", Type: cty.String, Required: false},
+		"size":         &hcldec.AttrSpec{Name: "size", Type: cty.Number, Required: false},
+		"sha256":       &hcldec.AttrSpec{Name: "sha256", Type: cty.String, Required: false},
+		"skipped":      &hcldec.AttrSpec{Name: "skipped", Type: cty.Bool, Required: false},
+	}
 	return s
 }
@@ -0,0 +1,515 @@
+package artifactDownloadOther
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"packer-plugin-artifactory/internal/testutil/fakeartifactory"
+)
+
+func TestDatasourceConfigure(t *testing.T) {
+	t.Setenv("ARTIFACTORY_TOKEN", "")
+	t.Setenv("ARTIFACTORY_SERVER", "")
+	t.Setenv("OUTPUTDIR", "")
+
+	valid := func() map[string]interface{} {
+		return map[string]interface{}{
+			"artifactory_token":  "token",
+			"artifactory_server": "https://server.com/artifactory/api",
+			"output_dir":         "/lab/",
+			"artifactory_path":   "/repo/folder/",
+			"file_list":          []string{"file1.txt"},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		remove  []string
+		set     map[string]interface{}
+		env     map[string]string
+		wantErr []string
+	}{
+		{name: "valid"},
+		{name: "negative parallelism", set: map[string]interface{}{"parallelism": -1}, wantErr: []string{"parallelism"}},
+		{name: "duplicate file", set: map[string]interface{}{"file_list": []string{"file1.txt", "file1.txt"}}, wantErr: []string{"\"file1.txt\" more than once"}},
+		{name: "file outside of output_dir", set: map[string]interface{}{"file_list": []string{"sub/file2.txt", "../../etc/cron.d/job", "/etc/passwd"}},
+			wantErr: []string{"\"../../etc/cron.d/job\" would be saved outside", "\"/etc/passwd\" would be saved outside"}},
+		{name: "include instead of file_list", remove: []string{"file_list"}, set: map[string]interface{}{"include": []string{"**/*.inf"}, "recursive": true}},
+		{name: "recursive alone", remove: []string{"file_list"}, set: map[string]interface{}{"recursive": true}},
+		{name: "file_list and include", set: map[string]interface{}{"include": []string{"*.txt"}}, wantErr: []string{"either 'file_list', or 'include'"}},
+		{name: "bad globs", remove: []string{"file_list"}, set: map[string]interface{}{"include": []string{"nic/[a-"}, "exclude": []string{""}},
+			wantErr: []string{"'include' pattern \"nic/[a-\" is not a valid glob", "'exclude' includes an empty pattern"}},
+		{name: "filter instead of a path", remove: []string{"artifactory_path", "file_list"}, set: map[string]interface{}{"filter": map[string]string{"bundle": "vmware-tools"}}},
+		{name: "filter and file_list", set: map[string]interface{}{"filter": map[string]string{"bundle": "vmware-tools"}}, wantErr: []string{"'filter' can't be used with 'file_list'"}},
+		{name: "extract_dir without extract", set: map[string]interface{}{"extract_dir": "/lab/extracted"}, wantErr: []string{"'extract_dir' is only used when 'extract' is true"}},
+		{name: "missing output_dir", remove: []string{"output_dir"}, wantErr: []string{"output_dir"}},
+		{name: "output_dir from OUTPUTDIR", remove: []string{"output_dir"}, env: map[string]string{"OUTPUTDIR": "/lab/"}},
+		{name: "missing artifactory_path", remove: []string{"artifactory_path"}, wantErr: []string{"artifactory_path"}},
+		{name: "missing everything", remove: []string{"artifactory_token", "artifactory_server", "output_dir", "artifactory_path", "file_list"},
+			wantErr: []string{"artifactory_token", "artifactory_server", "output_dir", "artifactory_path", "file_list"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := valid()
+			for _, key := range tt.remove {
+				delete(raw, key)
+			}
+			for key, value := range tt.set {
+				raw[key] = value
+			}
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			d := &Datasource{}
+			err := d.Configure(raw)
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			for _, field := range tt.wantErr {
+				if !strings.Contains(err.Error(), field) {
+					t.Errorf("expected %q to be reported, got: %s", field, err)
+				}
+			}
+		})
+	}
+}
+
+func TestDatasourceExecute(t *testing.T) {
+	server := fakeartifactory.New(t)
+	server.AddArtifact("/generic/tools/file1.txt", []byte("file one"), nil)
+	server.AddArtifact("/generic/tools/file2.txt", []byte("file two"), nil)
+
+	tests := []struct {
+		name      string
+		fileList  []string
+		wantFiles map[string]string
+	}{
+		{
+			name:      "single file",
+			fileList:  []string{"file1.txt"},
+			wantFiles: map[string]string{"file1.txt": "file one"},
+		},
+		{
+			name:      "several files",
+			fileList:  []string{"file1.txt", "file2.txt"},
+			wantFiles: map[string]string{"file1.txt": "file one", "file2.txt": "file two"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputDir := t.TempDir()
+
+			d := &Datasource{}
+			err := d.Configure(map[string]interface{}{
+				"artifactory_token":  fakeartifactory.Token,
+				"artifactory_server": server.ApiUrl(),
+				"output_dir":         outputDir,
+				"artifactory_path":   "/generic/tools/",
+				"file_list":          tt.fileList,
+			})
+			if err != nil {
+				t.Fatalf("Configure() error = %s", err)
+			}
+			if _, err := d.Execute(); err != nil {
+				t.Fatalf("Execute() error = %s", err)
+			}
+
+			for file, want := range tt.wantFiles {
+				got, err := os.ReadFile(filepath.Join(outputDir, file))
+				if err != nil {
+					t.Fatalf("expected %s to be downloaded: %s", file, err)
+				}
+				if string(got) != want {
+					t.Errorf("%s = %q, want %q", file, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDatasourceExecute_Parallel(t *testing.T) {
+	server := fakeartifactory.New(t)
+	var fileList []string
+	for i := 0; i < 12; i++ {
+		name := fmt.Sprintf("driver%02d.zip", i)
+		server.AddArtifact("/generic/drivers/"+name, []byte("contents of "+name), nil)
+		fileList = append(fileList, name)
+	}
+
+	tests := []struct {
+		name        string
+		parallelism int
+		fileList    []string
+		wantErr     []string
+	}{
+		{name: "default parallelism", fileList: fileList},
+		{name: "more workers than files", parallelism: 50, fileList: fileList[:3]},
+		{name: "failures are all reported", parallelism: 3, fileList: append([]string{"missing1.zip"}, append(fileList, "missing2.zip")...),
+			wantErr: []string{"2 of 14 file(s) failed", "missing1.zip", "missing2.zip"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputDir := t.TempDir()
+
+			d := &Datasource{}
+			err := d.Configure(map[string]interface{}{
+				"artifactory_token":  fakeartifactory.Token,
+				"artifactory_server": server.ApiUrl(),
+				"output_dir":         outputDir,
+				"artifactory_path":   "/generic/drivers",
+				"file_list":          tt.fileList,
+				"parallelism":        tt.parallelism,
+			})
+			if err != nil {
+				t.Fatalf("Configure() error = %s", err)
+			}
+			_, err = d.Execute()
+			if len(tt.wantErr) > 0 {
+				if err == nil {
+					t.Fatal("Execute() should fail")
+				}
+				for _, want := range tt.wantErr {
+					if !strings.Contains(err.Error(), want) {
+						t.Errorf("Execute() error = %s, want it to mention %q", err, want)
+					}
+				}
+			} else if err != nil {
+				t.Fatalf("Execute() error = %s", err)
+			}
+
+			// Every file that exists is downloaded, whether or not others failed
+			for _, file := range tt.fileList {
+				if strings.HasPrefix(file, "missing") {
+					continue
+				}
+				got, err := os.ReadFile(filepath.Join(outputDir, file))
+				if err != nil || string(got) != "contents of "+file {
+					t.Errorf("%s = %q, %v", file, got, err)
+				}
+			}
+		})
+	}
+}
+
+func TestDatasourceExecute_SkipExisting(t *testing.T) {
+	server := fakeartifactory.New(t)
+	server.AddArtifact("/generic/tools/same.txt", []byte("unchanged"), nil)
+	server.AddArtifact("/generic/tools/changed.txt", []byte("new contents"), nil)
+	server.AddArtifact("/generic/tools/resized.txt", []byte("longer than before"), nil)
+	server.AddArtifact("/generic/tools/new.txt", []byte("new file"), nil)
+
+	tests := []struct {
+		name         string
+		skipExisting bool
+		wantSkipped  int
+		wantBytes    int64
+	}{
+		{name: "off", skipExisting: false},
+		{name: "on", skipExisting: true, wantSkipped: 1, wantBytes: 9},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputDir := t.TempDir()
+			// changed.txt has the same size but different contents
+			local := map[string]string{"same.txt": "unchanged", "changed.txt": "old contents", "resized.txt": "short"}
+			for name, content := range local {
+				if err := os.WriteFile(filepath.Join(outputDir, name), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			before := len(server.Requests())
+
+			d := &Datasource{}
+			err := d.Configure(map[string]interface{}{
+				"artifactory_token":  fakeartifactory.Token,
+				"artifactory_server": server.ApiUrl(),
+				"output_dir":         outputDir,
+				"artifactory_path":   "/generic/tools",
+				"file_list":          []string{"same.txt", "changed.txt", "resized.txt", "new.txt"},
+				"skip_existing":      tt.skipExisting,
+			})
+			if err != nil {
+				t.Fatalf("Configure() error = %s", err)
+			}
+			value, err := d.Execute()
+			if err != nil {
+				t.Fatalf("Execute() error = %s", err)
+			}
+
+			for name, want := range map[string]string{"same.txt": "unchanged", "changed.txt": "new contents", "resized.txt": "longer than before", "new.txt": "new file"} {
+				if got, _ := os.ReadFile(filepath.Join(outputDir, name)); string(got) != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+			skipped, _ := value.GetAttr("files_skipped").AsBigFloat().Int64()
+			bytes, _ := value.GetAttr("bytes_skipped").AsBigFloat().Int64()
+			if int(skipped) != tt.wantSkipped || bytes != tt.wantBytes {
+				t.Errorf("files_skipped = %d, bytes_skipped = %d, want %d and %d", skipped, bytes, tt.wantSkipped, tt.wantBytes)
+			}
+			for _, download := range value.GetAttr("downloads").AsValueSlice() {
+				wantSkipped := tt.skipExisting && download.GetAttr("name").AsString() == "same.txt"
+				if download.GetAttr("skipped").True() != wantSkipped {
+					t.Errorf("%s skipped = %#v, want %t", download.GetAttr("name").AsString(), download.GetAttr("skipped"), wantSkipped)
+				}
+			}
+
+			downloaded := 0
+			for _, request := range server.Requests()[before:] {
+				if strings.HasPrefix(request, "GET /artifactory/generic/") {
+					downloaded++
+				}
+			}
+			if want := 4 - tt.wantSkipped; downloaded != want {
+				t.Errorf("downloaded %d files, want %d", downloaded, want)
+			}
+		})
+	}
+}
+
+func TestDatasourceExecute_Include(t *testing.T) {
+	server := fakeartifactory.New(t)
+	for _, repoPath := range []string{
+		"/generic/drivers/readme.txt",
+		"/generic/drivers/nic/intel/e1000.inf",
+		"/generic/drivers/nic/intel/e1000.sys",
+		"/generic/drivers/nic/intel/debug/e1000.pdb",
+		"/generic/drivers/storage/pvscsi.inf",
+		"/generic/drivers/storage/pvscsi.sys",
+		"/generic/other/unrelated.inf",
+	} {
+		server.AddArtifact(repoPath, []byte("contents of "+repoPath), nil)
+	}
+
+	tests := []struct {
+		name      string
+		config    map[string]interface{}
+		wantFiles []string
+		wantErr   string
+	}{
+		{
+			name:      "recursive keeps the folder structure",
+			config:    map[string]interface{}{"recursive": true, "exclude": []string{"**/debug/**"}},
+			wantFiles: []string{"nic/intel/e1000.inf", "nic/intel/e1000.sys", "readme.txt", "storage/pvscsi.inf", "storage/pvscsi.sys"},
+		},
+		{
+			name:      "file name pattern in every folder",
+			config:    map[string]interface{}{"recursive": true, "include": []string{"*.inf", "*.sys"}, "exclude": []string{"storage/*"}},
+			wantFiles: []string{"nic/intel/e1000.inf", "nic/intel/e1000.sys"},
+		},
+		{
+			name:      "not recursive",
+			config:    map[string]interface{}{"include": []string{"*"}},
+			wantFiles: []string{"readme.txt"},
+		},
+		{
+			name:      "path pattern",
+			config:    map[string]interface{}{"recursive": true, "include": []string{"nic/**/*.inf"}},
+			wantFiles: []string{"nic/intel/e1000.inf"},
+		},
+		{
+			name:    "nothing matches",
+			config:  map[string]interface{}{"recursive": true, "include": []string{"*.cab"}},
+			wantErr: "No files in /generic/drivers matched",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputDir := t.TempDir()
+			tt.config["artifactory_token"] = fakeartifactory.Token
+			tt.config["artifactory_server"] = server.ApiUrl()
+			tt.config["output_dir"] = outputDir
+			tt.config["artifactory_path"] = "/generic/drivers/"
+
+			d := &Datasource{}
+			if err := d.Configure(tt.config); err != nil {
+				t.Fatalf("Configure() error = %s", err)
+			}
+			_, err := d.Execute()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Execute() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Execute() error = %s", err)
+			}
+
+			var got []string
+			filepath.WalkDir(outputDir, func(localPath string, entry os.DirEntry, err error) error {
+				if err == nil && !entry.IsDir() {
+					relPath, _ := filepath.Rel(outputDir, localPath)
+					got = append(got, filepath.ToSlash(relPath))
+				}
+				return err
+			})
+			if strings.Join(got, ",") != strings.Join(tt.wantFiles, ",") {
+				t.Errorf("downloaded %v, want %v", got, tt.wantFiles)
+			}
+			for _, relPath := range got {
+				content, _ := os.ReadFile(filepath.Join(outputDir, relPath))
+				if string(content) != "contents of /generic/drivers/"+relPath {
+					t.Errorf("%s = %q", relPath, content)
+				}
+			}
+		})
+	}
+}
+
+func TestDatasourceExecute_Outputs(t *testing.T) {
+	server := fakeartifactory.New(t)
+	setup := server.AddArtifact("/generic/tools/setup.exe", []byte("setup"), nil)
+	inf := server.AddArtifact("/generic/tools/drivers/nic.inf", []byte("nic driver"), nil)
+
+	tests := []struct {
+		name   string
+		config map[string]interface{}
+		want   map[string]*fakeartifactory.Item
+	}{
+		{name: "file list", config: map[string]interface{}{"file_list": []string{"setup.exe"}}, want: map[string]*fakeartifactory.Item{"setup.exe": setup}},
+		{name: "recursive", config: map[string]interface{}{"recursive": true}, want: map[string]*fakeartifactory.Item{"drivers/nic.inf": inf, "setup.exe": setup}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputDir := t.TempDir()
+			tt.config["artifactory_token"] = fakeartifactory.Token
+			tt.config["artifactory_server"] = server.ApiUrl()
+			tt.config["output_dir"] = outputDir
+			tt.config["artifactory_path"] = "/generic/tools"
+
+			d := &Datasource{}
+			if err := d.Configure(tt.config); err != nil {
+				t.Fatalf("Configure() error = %s", err)
+			}
+			value, err := d.Execute()
+			if err != nil {
+				t.Fatalf("Execute() error = %s", err)
+			}
+
+			files := value.GetAttr("files").AsValueMap()
+			downloads := value.GetAttr("downloads").AsValueSlice()
+			if len(files) != len(tt.want) || len(downloads) != len(tt.want) {
+				t.Fatalf("files = %v, downloads = %v, want %d of each", files, downloads, len(tt.want))
+			}
+			for _, download := range downloads {
+				name := download.GetAttr("name").AsString()
+				item, ok := tt.want[name]
+				if !ok {
+					t.Errorf("unexpected download %s", name)
+					continue
+				}
+				localPath := filepath.Join(outputDir, filepath.FromSlash(name))
+				if got := files[name].AsString(); got != localPath {
+					t.Errorf("files[%q] = %s, want %s", name, got, localPath)
+				}
+				if got := download.GetAttr("local_path").AsString(); got != localPath {
+					t.Errorf("%s local_path = %s, want %s", name, got, localPath)
+				}
+				if got := download.GetAttr("download_uri").AsString(); got != server.DownloadUrl(item.RepoPath()) {
+					t.Errorf("%s download_uri = %s", name, got)
+				}
+				if got := download.GetAttr("sha256").AsString(); got != item.Sha256() {
+					t.Errorf("%s sha256 = %s, want %s", name, got, item.Sha256())
+				}
+				if size, _ := download.GetAttr("size").AsBigFloat().Int64(); size != int64(len(item.Content)) {
+					t.Errorf("%s size = %d", name, size)
+				}
+				if download.GetAttr("skipped").True() {
+					t.Errorf("%s should not be skipped", name)
+				}
+			}
+		})
+	}
+}
+
+func TestDatasourceExecute_Extract(t *testing.T) {
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for name, content := range map[string]string{"drivers/nic.inf": "nic driver", "setup.ps1": "Write-Host setup"} {
+		w, _ := writer.Create(name)
+		w.Write([]byte(content))
+	}
+	writer.Close()
+
+	server := fakeartifactory.New(t)
+	server.AddArtifact("/generic/bundles/tools.zip", buf.Bytes(), nil)
+	server.AddArtifact("/generic/bundles/readme.txt", []byte("read me"), nil)
+	server.AddArtifact("/generic/bundles/broken.tar.gz", []byte("not gzip"), nil)
+
+	tests := []struct {
+		name       string
+		fileList   []string
+		extractDir string
+		want       []string
+		wantErr    string
+	}{
+		{name: "into output_dir", fileList: []string{"tools.zip", "readme.txt"}, want: []string{"drivers/nic.inf", "setup.ps1"}},
+		{name: "into extract_dir", fileList: []string{"tools.zip"}, extractDir: "extracted", want: []string{"extracted/drivers/nic.inf", "extracted/setup.ps1"}},
+		{name: "no archives", fileList: []string{"readme.txt"}, want: []string{}},
+		{name: "broken archive", fileList: []string{"broken.tar.gz"}, wantErr: "Unable to extract"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputDir := t.TempDir()
+			config := map[string]interface{}{
+				"artifactory_token":  fakeartifactory.Token,
+				"artifactory_server": server.ApiUrl(),
+				"output_dir":         outputDir,
+				"artifactory_path":   "/generic/bundles",
+				"file_list":          tt.fileList,
+				"extract":            true,
+			}
+			if tt.extractDir != "" {
+				config["extract_dir"] = filepath.Join(outputDir, tt.extractDir)
+			}
+
+			d := &Datasource{}
+			if err := d.Configure(config); err != nil {
+				t.Fatalf("Configure() error = %s", err)
+			}
+			value, err := d.Execute()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Execute() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Execute() error = %s", err)
+			}
+
+			var got []string
+			for _, extracted := range value.GetAttr("extracted_files").AsValueSlice() {
+				relPath, _ := filepath.Rel(outputDir, extracted.AsString())
+				got = append(got, filepath.ToSlash(relPath))
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("extracted_files = %v, want %v", got, tt.want)
+			}
+			for _, relPath := range tt.want {
+				if _, err := os.Stat(filepath.Join(outputDir, filepath.FromSlash(relPath))); err != nil {
+					t.Errorf("%s was not extracted: %s", relPath, err)
+				}
+			}
+		})
+	}
+}
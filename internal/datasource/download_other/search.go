@@ -0,0 +1,120 @@
+package artifactDownloadOther
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"packer-plugin-artifactory/internal/client"
+	"packer-plugin-artifactory/internal/download"
+)
+
+// BuildPropertyQuery returns the AQL query for files with every property in the filter, within the /repo/folder
+// path and the folders below it if one is given. A value with '*' or '?' is matched as a wildcard.
+func BuildPropertyQuery(filter map[string]string, folder string) (string, error) {
+	clauses := []map[string]interface{}{{"type": "file"}}
+
+	repo, folderPath, _ := strings.Cut(strings.Trim(folder, "/"), "/")
+	if repo != "" {
+		clauses = append(clauses, map[string]interface{}{"repo": repo})
+	}
+	if folderPath != "" {
+		clauses = append(clauses, map[string]interface{}{"$or": []map[string]interface{}{
+			{"path": folderPath},
+			{"path": map[string]string{"$match": folderPath + "/*"}},
+		}})
+	}
+
+	keys := make([]string, 0, len(filter))
+	for key := range filter {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := filter[key]
+		if strings.ContainsAny(value, "*?") {
+			clauses = append(clauses, map[string]interface{}{"@" + key: map[string]string{"$match": value}})
+		} else {
+			clauses = append(clauses, map[string]interface{}{"@" + key: value})
+		}
+	}
+
+	criteria, err := json.Marshal(map[string]interface{}{"$and": clauses})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(`items.find(%s).include("repo","path","name","size","modified","sha256","actual_sha1")`, criteria), nil
+}
+
+// searchFiles returns the files with the properties in 'filter', less any the 'include' and 'exclude'
+// patterns leave out. With a folder, files keep their structure below it in outputDir; without one, they are
+// saved directly in outputDir and must have different names.
+func (d *Datasource) searchFiles(ctx context.Context, artifClient *client.Client, folder, outputDir string) ([]download.File, error) {
+	query, err := BuildPropertyQuery(d.config.Filter, folder)
+	if err != nil {
+		return nil, err
+	}
+	items, err := artifClient.SearchAql(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].RepoPath() < items[j].RepoPath() })
+
+	folder = strings.Trim(folder, "/")
+	var files []download.File
+	savedFrom := map[string]string{}
+	for _, item := range items {
+		repoPath := item.RepoPath()
+		relPath := path.Base(repoPath)
+		if folder != "" {
+			relPath = strings.TrimPrefix(repoPath, "/"+folder+"/")
+		}
+		if len(d.config.Include) > 0 && !matchesAny(d.config.Include, relPath) {
+			continue
+		}
+		if matchesAny(d.config.Exclude, relPath) {
+			continue
+		}
+
+		if other, ok := savedFrom[relPath]; ok {
+			return nil, fmt.Errorf("Both %s and %s would be saved as %s; set 'artifactory_path' to keep the folder structure, "+
+				"or use 'exclude' to leave one out", other, repoPath, relPath)
+		}
+		savedFrom[relPath] = repoPath
+		localPath := filepath.FromSlash(relPath)
+		if !filepath.IsLocal(localPath) {
+			return nil, fmt.Errorf("%s would be saved outside of the output directory", repoPath)
+		}
+
+		files = append(files, download.File{
+			FileInfo: client.FileInfo{
+				RepoPath: repoPath,
+				Size:     item.Size,
+				Sha256:   item.Sha256,
+				Sha1:     item.ActualSha1,
+				Modified: item.Modified,
+			},
+			LocalPath: filepath.Join(outputDir, localPath),
+		})
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("No files matched 'filter' %s (%d file(s) had the properties before 'include' and 'exclude')",
+			FilterString(d.config.Filter), len(items))
+	}
+	return files, nil
+}
+
+// FilterString renders the filter as sorted key=value pairs for messages.
+func FilterString(filter map[string]string) string {
+	pairs := make([]string, 0, len(filter))
+	for key, value := range filter {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
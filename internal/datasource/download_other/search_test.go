@@ -0,0 +1,114 @@
+package artifactDownloadOther
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"packer-plugin-artifactory/internal/testutil/fakeartifactory"
+)
+
+func TestBuildPropertyQuery(t *testing.T) {
+	tests := []struct {
+		folder string
+		filter map[string]string
+		want   string
+	}{
+		{"", map[string]string{"version": "12.3", "bundle": "vmware-tools"},
+			`items.find({"$and":[{"type":"file"},{"@bundle":"vmware-tools"},{"@version":"12.3"}]})`},
+		{"/tools/", map[string]string{"version": "12.*"},
+			`items.find({"$and":[{"type":"file"},{"repo":"tools"},{"@version":{"$match":"12.*"}}]})`},
+		{"/tools/vmware", map[string]string{"bundle": "vmware-tools"},
+			`items.find({"$and":[{"type":"file"},{"repo":"tools"},{"$or":[{"path":"vmware"},{"path":{"$match":"vmware/*"}}]},{"@bundle":"vmware-tools"}]})`},
+	}
+	for _, tt := range tests {
+		got, err := BuildPropertyQuery(tt.filter, tt.folder)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := tt.want + `.include("repo","path","name","size","modified","sha256","actual_sha1")`
+		if got != want {
+			t.Errorf("BuildPropertyQuery(%v, %q) =\n%s\nwant\n%s", tt.filter, tt.folder, got, want)
+		}
+	}
+}
+
+func TestDatasourceExecute_Filter(t *testing.T) {
+	server := fakeartifactory.New(t)
+	tools := map[string]string{"bundle": "vmware-tools", "version": "12.3"}
+	server.AddArtifact("/tools/vmware/12.3/setup64.exe", []byte("setup64"), tools)
+	server.AddArtifact("/tools/vmware/12.3/drivers/pvscsi.inf", []byte("pvscsi"), tools)
+	server.AddArtifact("/tools/vmware/12.3/debug.pdb", []byte("debug"), tools)
+	server.AddArtifact("/tools/vmware/12.2/setup64.exe", []byte("old setup64"), map[string]string{"bundle": "vmware-tools", "version": "12.2"})
+	server.AddArtifact("/mirror/vmware/setup64.exe", []byte("mirrored setup64"), tools)
+
+	tests := []struct {
+		name      string
+		config    map[string]interface{}
+		wantFiles map[string]string
+		wantErr   string
+	}{
+		{
+			name:      "folder keeps the structure",
+			config:    map[string]interface{}{"artifactory_path": "/tools/vmware", "exclude": []string{"*.pdb"}},
+			wantFiles: map[string]string{"12.3/setup64.exe": "setup64", "12.3/drivers/pvscsi.inf": "pvscsi"},
+		},
+		{
+			name:      "no folder saves by name",
+			config:    map[string]interface{}{"include": []string{"*.inf"}},
+			wantFiles: map[string]string{"pvscsi.inf": "pvscsi"},
+		},
+		{
+			name:    "same name in two places",
+			config:  map[string]interface{}{"include": []string{"setup64.exe"}},
+			wantErr: "would be saved as setup64.exe",
+		},
+		{
+			name:    "nothing has the properties",
+			config:  map[string]interface{}{"artifactory_path": "/mirror", "include": []string{"*.inf"}},
+			wantErr: "No files matched 'filter' bundle=vmware-tools, version=12.3 (1 file(s)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputDir := t.TempDir()
+			tt.config["artifactory_token"] = fakeartifactory.Token
+			tt.config["artifactory_server"] = server.ApiUrl()
+			tt.config["output_dir"] = outputDir
+			tt.config["filter"] = tools
+
+			d := &Datasource{}
+			if err := d.Configure(tt.config); err != nil {
+				t.Fatalf("Configure() error = %s", err)
+			}
+			_, err := d.Execute()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Execute() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Execute() error = %s", err)
+			}
+
+			found := 0
+			filepath.WalkDir(outputDir, func(localPath string, entry os.DirEntry, err error) error {
+				if err == nil && !entry.IsDir() {
+					found++
+				}
+				return err
+			})
+			if found != len(tt.wantFiles) {
+				t.Errorf("downloaded %d files, want %d", found, len(tt.wantFiles))
+			}
+			for relPath, want := range tt.wantFiles {
+				if got, _ := os.ReadFile(filepath.Join(outputDir, filepath.FromSlash(relPath))); string(got) != want {
+					t.Errorf("%s = %q, want %q", relPath, got, want)
+				}
+			}
+		})
+	}
+}
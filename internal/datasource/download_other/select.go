@@ -0,0 +1,111 @@
+package artifactDownloadOther
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"packer-plugin-artifactory/internal/client"
+	"packer-plugin-artifactory/internal/download"
+)
+
+// PrepareGlobs checks that every pattern is a valid glob. The setting name is used in the errors.
+func PrepareGlobs(setting string, patterns []string) []error {
+	var errs []error
+	for _, pattern := range patterns {
+		if strings.TrimSpace(pattern) == "" {
+			errs = append(errs, fmt.Errorf("'%s' includes an empty pattern.", setting))
+			continue
+		}
+		for _, segment := range strings.Split(pattern, "/") {
+			if _, err := path.Match(segment, ""); err != nil {
+				errs = append(errs, fmt.Errorf("'%s' pattern %q is not a valid glob: %s", setting, pattern, err))
+				break
+			}
+		}
+	}
+	return errs
+}
+
+// MatchGlob reports whether the relative path (ex: nic/intel/driver.inf) matches the glob pattern. '*', '?', and
+// '[...]' match within one folder level, and a '**' segment matches any number of folder levels (at least one
+// at the end of the pattern). A pattern without a '/' is matched against the file name alone, so '*.inf' finds
+// .inf files in every folder.
+func MatchGlob(pattern, relPath string) bool {
+	if !strings.Contains(pattern, "/") {
+		matched, _ := path.Match(pattern, path.Base(relPath))
+		return matched
+	}
+	return matchSegments(strings.Split(strings.Trim(pattern, "/"), "/"), strings.Split(relPath, "/"))
+}
+
+func matchSegments(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// A trailing ** matches everything inside the folder, but not a file named after the folder
+			if len(pattern) == 1 {
+				return len(segments) > 0
+			}
+			for skip := 0; skip <= len(segments); skip++ {
+				if matchSegments(pattern[1:], segments[skip:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		if matched, _ := path.Match(pattern[0], segments[0]); !matched {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
+
+// matchesAny reports whether the relative path matches any of the patterns.
+func matchesAny(patterns []string, relPath string) bool {
+	for _, pattern := range patterns {
+		if MatchGlob(pattern, relPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// listFiles returns the files under the /repo/folder path that match an include pattern (or every file, if there
+// are none) and no exclude pattern, to be saved under outputDir with the same folder structure. Only files
+// directly in the folder are listed unless recursive is set.
+func (d *Datasource) listFiles(ctx context.Context, artifClient *client.Client, folder, outputDir string) ([]download.File, error) {
+	folder = "/" + strings.Trim(folder, "/")
+	listed, err := artifClient.ListFiles(ctx, folder, d.config.Recursive)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(listed, func(i, j int) bool { return listed[i].RepoPath < listed[j].RepoPath })
+	var files []download.File
+	for _, info := range listed {
+		relPath := strings.TrimPrefix(info.RepoPath, folder+"/")
+		if len(d.config.Include) > 0 && !matchesAny(d.config.Include, relPath) {
+			continue
+		}
+		if matchesAny(d.config.Exclude, relPath) {
+			continue
+		}
+		localPath := filepath.FromSlash(relPath)
+		if !filepath.IsLocal(localPath) {
+			return nil, fmt.Errorf("%s would be saved outside of the output directory", info.RepoPath)
+		}
+		files = append(files, download.File{FileInfo: info, LocalPath: filepath.Join(outputDir, localPath)})
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("No files in %s matched the 'include' and 'exclude' patterns (%d file(s) were listed)", folder, len(listed))
+	}
+	return files, nil
+}
@@ -0,0 +1,30 @@
+package artifactDownloadOther
+
+import "testing"
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		matches []string
+		misses  []string
+	}{
+		{"*.inf", []string{"e1000.inf", "nic/intel/e1000.inf"}, []string{"e1000.sys", "inf/e1000.sys"}},
+		{"nic/*/*.inf", []string{"nic/intel/e1000.inf"}, []string{"nic/e1000.inf", "nic/intel/x64/e1000.inf"}},
+		{"nic/**", []string{"nic/e1000.inf", "nic/intel/x64/e1000.inf"}, []string{"storage/pvscsi.inf", "nic"}},
+		{"**/debug/**", []string{"debug/a.pdb", "nic/intel/debug/a.pdb"}, []string{"nic/debugger.exe"}},
+		{"nic/**/*.sys", []string{"nic/e1000.sys", "nic/intel/x64/e1000.sys"}, []string{"storage/pvscsi.sys"}},
+		{"/readme.txt", []string{"readme.txt"}, []string{"nic/readme.txt"}},
+	}
+	for _, tt := range tests {
+		for _, relPath := range tt.matches {
+			if !MatchGlob(tt.pattern, relPath) {
+				t.Errorf("%q should match %s", tt.pattern, relPath)
+			}
+		}
+		for _, relPath := range tt.misses {
+			if MatchGlob(tt.pattern, relPath) {
+				t.Errorf("%q should not match %s", tt.pattern, relPath)
+			}
+		}
+	}
+}
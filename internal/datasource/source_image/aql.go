@@ -0,0 +1,275 @@
+package artifactImage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"packer-plugin-artifactory/internal/client"
+)
+
+// --> If making changes to this section, make sure the hcl2spec gets updated as well!
+// AqlConfig selects artifacts with an Artifactory Query Language search instead of the name search.
+// Either provide a raw query, or any of the structured fields (which are combined with AND).
+type AqlConfig struct {
+	// Raw AQL query, ex: items.find({"repo":"images","name":{"$match":"win22*"}})
+	Query string `mapstructure:"query" required:"false"`
+	// Repositories to search; an artifact in any of them matches
+	Repositories []string `mapstructure:"repositories" required:"false"`
+	// Folder path within the repository; supports '*' and '?' wildcards (ex: windows/win22*)
+	Path string `mapstructure:"path" required:"false"`
+	// File name; supports '*' and '?' wildcards (ex: win22-*.ova)
+	Name string `mapstructure:"name" required:"false"`
+	// Key/value pairs of properties the artifact must have
+	Properties map[string]string `mapstructure:"properties" required:"false"`
+	// RFC3339 timestamps (ex: 2024-01-31T00:00:00Z) bounding the creation date
+	CreatedAfter  string `mapstructure:"created_after" required:"false"`
+	CreatedBefore string `mapstructure:"created_before" required:"false"`
+}
+
+func (a *AqlConfig) structured() bool {
+	return len(a.Repositories) > 0 || a.Path != "" || a.Name != "" || len(a.Properties) > 0 || a.CreatedAfter != "" || a.CreatedBefore != ""
+}
+
+// Prepare validates the block; artifact name, channel, and filter are the datasource settings that get merged into the query.
+func (a *AqlConfig) Prepare(artifName string, kvInput map[string]string, channel string) []error {
+	var errs []error
+
+	if a.Query != "" {
+		if a.structured() {
+			errs = append(errs, errors.New("The 'aql' block takes either a raw 'query' or the structured fields (repositories, path, name, properties, created_after, created_before), not both."))
+		}
+		if !strings.HasPrefix(strings.TrimSpace(a.Query), "items.find(") {
+			errs = append(errs, errors.New("The 'aql' 'query' must be an items.find(...) query."))
+		}
+		if artifName != "" || len(kvInput) > 0 || channel != "" {
+			errs = append(errs, errors.New("'artifact_name', 'filter', and 'channel' cannot be combined with a raw 'aql' 'query'; add those conditions to the query instead."))
+		}
+	} else if !a.structured() && artifName == "" {
+		errs = append(errs, errors.New("The 'aql' block needs a raw 'query', at least one structured field, or an 'artifact_name' to search for."))
+	}
+
+	for _, date := range []struct{ name, value string }{{"created_after", a.CreatedAfter}, {"created_before", a.CreatedBefore}} {
+		if date.value == "" {
+			continue
+		}
+		if _, err := time.Parse(time.RFC3339, date.value); err != nil {
+			errs = append(errs, fmt.Errorf("The 'aql' '%s' value %q is not an RFC3339 timestamp (ex: 2024-01-31T00:00:00Z).", date.name, date.value))
+		}
+	}
+	return errs
+}
+
+// AqlSearch holds the datasource settings that are merged into a structured AQL query.
+type AqlSearch struct {
+	ArtifactName string
+	NameMatch    string
+	// Properties the artifact must have, from 'filter' and 'channel'
+	Properties map[string]string
+	Filters    []PropertyFilter
+	Scope      Scope
+	// Only added to the query when the name doesn't narrow it down
+	FileTypes []string
+}
+
+// BuildAqlQuery returns the query to run: the raw query when one was given, otherwise an items.find built from the
+// structured fields plus the datasource's search settings. In AQL, the property comparisons are case sensitive; the
+// artifact name is only narrowed down here, and compared for real by FilterByName, as are the property filters AQL
+// can't express by FilterByPropertyFilters.
+func BuildAqlQuery(a *AqlConfig, search AqlSearch) (string, error) {
+	if a.Query != "" {
+		return strings.TrimSpace(a.Query), nil
+	}
+
+	clauses := []map[string]interface{}{{"type": "file"}}
+
+	if len(a.Repositories) == 1 {
+		clauses = append(clauses, map[string]interface{}{"repo": a.Repositories[0]})
+	} else if len(a.Repositories) > 1 {
+		var repos []map[string]interface{}
+		for _, repo := range a.Repositories {
+			repos = append(repos, map[string]interface{}{"repo": repo})
+		}
+		clauses = append(clauses, map[string]interface{}{"$or": repos})
+	}
+	clauses = append(clauses, search.Scope.aqlCriteria()...)
+
+	if a.Path != "" {
+		clauses = append(clauses, map[string]interface{}{"path": map[string]string{"$match": strings.Trim(a.Path, "/")}})
+	}
+	if a.Name != "" {
+		clauses = append(clauses, map[string]interface{}{"name": map[string]string{"$match": a.Name}})
+	}
+	var nameCriteria map[string]interface{}
+	if search.ArtifactName != "" {
+		nameCriteria = nameAqlCriteria(search.NameMatch, search.ArtifactName)
+	}
+	if nameCriteria != nil {
+		clauses = append(clauses, nameCriteria)
+	} else if a.Name == "" && len(search.FileTypes) > 0 {
+		var types []map[string]interface{}
+		for _, ext := range search.FileTypes {
+			types = append(types, map[string]interface{}{"name": map[string]string{"$match": "*" + ext}})
+		}
+		if len(types) == 1 {
+			clauses = append(clauses, types[0])
+		} else {
+			clauses = append(clauses, map[string]interface{}{"$or": types})
+		}
+	}
+
+	props := map[string]string{}
+	for key, value := range a.Properties {
+		props[key] = value
+	}
+	for key, value := range search.Properties {
+		props[key] = value
+	}
+	keys := make([]string, 0, len(props))
+	for key := range props {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		clauses = append(clauses, map[string]interface{}{"@" + key: props[key]})
+	}
+	for _, filter := range search.Filters {
+		if criteria := filter.aqlCriteria(); criteria != nil {
+			clauses = append(clauses, criteria)
+		}
+	}
+
+	if a.CreatedAfter != "" {
+		clauses = append(clauses, map[string]interface{}{"created": map[string]string{"$gt": a.CreatedAfter}})
+	}
+	if a.CreatedBefore != "" {
+		clauses = append(clauses, map[string]interface{}{"created": map[string]string{"$lt": a.CreatedBefore}})
+	}
+
+	criteria, err := json.Marshal(map[string]interface{}{"$and": clauses})
+	if err != nil {
+		return "", err
+	}
+	return "items.find(" + string(criteria) + ")", nil
+}
+
+// aqlFields lists the fields every AQL search returns, so the outputs, the filters, and the lifecycle and lockfile
+// checks have what they need without further requests.
+var aqlFields = []string{"repo", "path", "name", "type", "size", "created", "created_by", "modified", "modified_by", "updated",
+	"sha256", "actual_sha1", "actual_md5", "property.*"}
+
+// WithAqlInclude makes sure the query returns aqlFields. A query without an include clause gets one right after its
+// find(...); the fields missing from a query's own include clause are added to it.
+func WithAqlInclude(query string) string {
+	if start := strings.Index(query, ".include("); start >= 0 {
+		open := start + len(".include")
+		end := closingParen(query, open)
+		if end < 0 {
+			return query
+		}
+		fields := splitAqlArgs(query[open+1 : end])
+		seen := map[string]bool{}
+		for _, field := range fields {
+			seen[strings.Trim(field, `"`)] = true
+		}
+		for _, field := range aqlFields {
+			if !seen[field] {
+				fields = append(fields, `"`+field+`"`)
+			}
+		}
+		return query[:open+1] + strings.Join(fields, ",") + query[end:]
+	}
+
+	start := strings.Index(query, "find(")
+	if start < 0 {
+		return query
+	}
+	end := closingParen(query, start+len("find"))
+	if end < 0 {
+		return query
+	}
+	return query[:end+1] + `.include("` + strings.Join(aqlFields, `","`) + `")` + query[end+1:]
+}
+
+// closingParen returns the index of the parenthesis closing the one at open, skipping over quoted strings, or -1.
+func closingParen(query string, open int) int {
+	depth, inString := 0, false
+	for i := open; i < len(query); i++ {
+		switch c := query[i]; {
+		case inString && c == '\\':
+			i++
+		case c == '"':
+			inString = !inString
+		case inString:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// splitAqlArgs splits the comma separated arguments of an include clause, trimmed, keeping quoted commas.
+func splitAqlArgs(args string) []string {
+	var fields []string
+	start, inString := 0, false
+	for i := 0; i <= len(args); i++ {
+		switch {
+		case i == len(args) || (!inString && args[i] == ','):
+			if field := strings.TrimSpace(args[start:i]); field != "" {
+				fields = append(fields, field)
+			}
+			start = i + 1
+		case args[i] == '\\' && inString:
+			i++
+		case args[i] == '"':
+			inString = !inString
+		}
+	}
+	return fields
+}
+
+// searchAql runs the AQL search and returns every artifact it finds, along with the query that was run.
+func (d *Datasource) searchAql(artifClient *client.Client, aql *AqlConfig) ([]client.Artifact, string, error) {
+	filter := map[string]string{}
+	for key, value := range d.config.ArtifactFilter {
+		filter[key] = value
+	}
+	if d.config.ArtifactChannel != "" {
+		filter["channel"] = d.config.ArtifactChannel
+	}
+
+	query, err := BuildAqlQuery(aql, AqlSearch{
+		ArtifactName: d.config.ArtifactName,
+		NameMatch:    d.config.NameMatch,
+		Properties:   filter,
+		Filters:      d.config.PropertyFilters,
+		Scope:        d.scope(),
+		FileTypes:    d.config.ArtifactFileTypes,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	items, err := artifClient.SearchAql(context.Background(), WithAqlInclude(query))
+	if err != nil {
+		return nil, query, err
+	}
+
+	var artifacts []client.Artifact
+	for _, item := range items {
+		if item.Type != "" && item.Type != "file" {
+			continue
+		}
+		artifacts = append(artifacts, item.Artifact())
+	}
+	return artifacts, query, nil
+}
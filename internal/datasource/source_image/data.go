@@ -1,41 +1,137 @@
-//go:generate packer-sdc mapstructure-to-hcl2 -type Config,DatasourceOutput
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config,AqlConfig,PropertyFilter,DatasourceOutput,ArtifactOutput
 package artifactImage
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"os"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 
+	"packer-plugin-artifactory/internal/client"
+
+	"github.com/hashicorp/go-version"
 	"github.com/hashicorp/hcl/v2/hcldec"
 	"github.com/hashicorp/packer-plugin-sdk/hcl2helper"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	"github.com/hashicorp/packer-plugin-sdk/template/config"
-	"github.com/raynaluzier/artifactory-go-sdk/tasks"
 	"github.com/zclconf/go-cty/cty"
 )
 
 // --> If making changes to this section, make sure the hcl2spec gets updated as well!
 type Config struct {
-	ArtifactoryToken       string `mapstructure:"artifactory_token" required:"true"`
-	ArtifactoryServer      string `mapstructure:"artifactory_server" required:"true"`
+	client.ConnectionConfig `mapstructure:",squash"`
+
 	// Full or partial name of the artifact
 	ArtifactName           string `mapstructure:"artifact_name" required:"true"`
-	// File extension; defaults to '.vmtx' if left blank
-	ArtifactFileType       string `mapstructure:"file_type" required:"true"`
+	// How artifact_name is compared with file names: contains (default), exact, prefix, glob, or regex
+	NameMatch              string `mapstructure:"name_match" required:"false"`
+	// File extension; defaults to '.vmtx' if neither file_type nor file_types is set
+	ArtifactFileType       string `mapstructure:"file_type" required:"false"`
+	// File extensions in order of preference (ex: ["ova", "ovf"]); the first one with a match is used
+	ArtifactFileTypes      []string `mapstructure:"file_types" required:"false"`
+	// Repository keys to search; every repository the token can read is searched if left blank
+	Repositories           []string `mapstructure:"repositories" required:"false"`
+	// Only consider artifacts in this folder, or below it, within the repositories (ex: windows/prod)
+	PathPrefix             string `mapstructure:"path_prefix" required:"false"`
 	// Channel is technically a property; if it exists, will be appended to the kvProperties []string
 	ArtifactChannel        string `mapstructure:"channel" required:"false"`
+	// Property marking an image that must not be used; defaults to 'revoked'
+	RevokedProperty        string `mapstructure:"revoked_property" required:"false"`
+	// Property marking an image that still resolves, with a warning; defaults to 'deprecated'
+	DeprecatedProperty     string `mapstructure:"deprecated_property" required:"false"`
 	// Key/value pairs of properties to filter on
 	ArtifactFilter         map[string]string `mapstructure:"filter" required:"false"`
+	// Property conditions beyond equality (not equal, any of, exists, absent, wildcard, regex); all must pass
+	PropertyFilters        []PropertyFilter `mapstructure:"property_filter" required:"false"`
+	// RFC3339 timestamps (ex: 2024-01-31T00:00:00Z) bounding the creation date
+	CreatedAfter           string `mapstructure:"created_after" required:"false"`
+	CreatedBefore          string `mapstructure:"created_before" required:"false"`
+	// Only consider artifacts created within this duration of now (ex: 720h)
+	MaxAge                 string `mapstructure:"max_age" required:"false"`
+	// Return empty outputs instead of failing when no artifact matches; defaults to false
+	AllowEmpty             bool `mapstructure:"allow_empty" required:"false"`
+	// How to choose between several matches: latest_created (default), latest_modified, oldest, highest_semver, or unique
+	Select                 string `mapstructure:"select" required:"false"`
+	// Property holding the artifact's semantic version; the version is parsed from the file name if left blank
+	VersionProperty        string `mapstructure:"version_property" required:"false"`
+	// Only consider artifacts whose version satisfies the constraint, ex: ">= 2.3, < 3.0"; implies select = "highest_semver"
+	VersionConstraint      string `mapstructure:"version_constraint" required:"false"`
+	// Search with AQL instead of the name search; artifact_name becomes optional
+	Aql                    *AqlConfig `mapstructure:"aql" required:"false"`
+	// Path to a JSON lockfile (ex: artifactory.lock.json); once an artifact is recorded there, later builds reuse it
+	Lockfile               string `mapstructure:"lockfile" required:"false"`
+	// Name of this datasource's entry in the lockfile; defaults to artifact_name
+	LockKey                string `mapstructure:"lock_key" required:"false"`
+	// Search again and replace the pinned artifact; also set with the ARTIFACTORY_LOCK_UPDATE environment variable
+	LockUpdate             bool `mapstructure:"lock_update" required:"false"`
 }
 
 type Datasource struct {
-	config Config
+	config      Config
+	nameMatcher NameMatcher
 }
 
 // --> If making changes to this section, make sure the hcl2spec gets updated as well!
 type DatasourceOutput struct {
 	Name        string `mapstructure:"name"`
+	// Extension of the selected artifact without the leading '.', ex: ova; with 'file_types', the format that was chosen
+	FileType    string `mapstructure:"file_type"`
+	// RFC3339, ex: 2024-01-05T14:21:03Z
 	Created     string `mapstructure:"creation_date"`
+	// Unix timestamp of the creation date
+	CreatedUnix int64  `mapstructure:"creation_timestamp"`
 	ArtifactUri	string `mapstructure:"artifact_uri"`
 	DownloadUri string `mapstructure:"download_uri"`
+	Sha256      string `mapstructure:"sha256"`
+	Sha1        string `mapstructure:"sha1"`
+	Md5         string `mapstructure:"md5"`
+	// Size in bytes
+	Size        int64  `mapstructure:"size"`
+	Modified    string `mapstructure:"last_modified"`
+	CreatedBy   string `mapstructure:"created_by"`
+	ModifiedBy  string `mapstructure:"modified_by"`
+	Repo        string `mapstructure:"repo"`
+	// Folder path within the repository; empty for the repository root
+	Path        string `mapstructure:"path"`
+	// Properties with several values have them joined by commas
+	Properties  map[string]string `mapstructure:"properties"`
+	// Always false for the selected artifact, since a revoked artifact fails the lookup; kept alongside the
+	// 'artifacts' entries, where it can be true
+	Revoked     bool   `mapstructure:"revoked"`
+	Deprecated  bool   `mapstructure:"deprecated"`
+	DeprecationReason string `mapstructure:"deprecation_reason"`
+	// Every artifact that matched, of the chosen file type, in 'select' order; the first entry is the one described above
+	Artifacts   []ArtifactOutput `mapstructure:"artifacts"`
+}
+
+// --> If making changes to this section, make sure the hcl2spec gets updated as well!
+type ArtifactOutput struct {
+	Name        string `mapstructure:"name"`
+	FileType    string `mapstructure:"file_type"`
+	ArtifactUri string `mapstructure:"artifact_uri"`
+	DownloadUri string `mapstructure:"download_uri"`
+	Created     string `mapstructure:"creation_date"`
+	CreatedUnix int64  `mapstructure:"creation_timestamp"`
+	Modified    string `mapstructure:"last_modified"`
+	CreatedBy   string `mapstructure:"created_by"`
+	ModifiedBy  string `mapstructure:"modified_by"`
+	Sha256      string `mapstructure:"sha256"`
+	Sha1        string `mapstructure:"sha1"`
+	Md5         string `mapstructure:"md5"`
+	Size        int64  `mapstructure:"size"`
+	Repo        string `mapstructure:"repo"`
+	// Folder path within the repository; empty for the repository root
+	Path        string `mapstructure:"path"`
+	// Properties with several values have them joined by commas
+	Properties  map[string]string `mapstructure:"properties"`
+	Revoked     bool   `mapstructure:"revoked"`
+	Deprecated  bool   `mapstructure:"deprecated"`
+	DeprecationReason string `mapstructure:"deprecation_reason"`
 }
 
 func (d *Datasource) ConfigSpec() hcldec.ObjectSpec { 
@@ -48,26 +144,94 @@ func (d *Datasource) Configure(raws ...interface{}) error {
 		return err
 	}
 
-	if d.config.ArtifactoryToken == "" {
-		token := os.Getenv("ARTIFACTORY_TOKEN")
-		if token == "" {
-			log.Fatal("---> Please provide an Artifactory Identity Token.")
+	var errs *packersdk.MultiError
+	errs = packersdk.MultiErrorAppend(errs, d.config.ConnectionConfig.Prepare()...)
+
+	if d.config.Aql != nil {
+		errs = packersdk.MultiErrorAppend(errs, d.config.Aql.Prepare(d.config.ArtifactName, d.config.ArtifactFilter, d.config.ArtifactChannel)...)
+	} else if d.config.ArtifactName == "" {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("Please provide the full or partial artifact name with 'artifact_name'."))
+	}
+
+	if d.config.NameMatch == "" {
+		d.config.NameMatch = NameContains
+	}
+	nameMatcher, nameErrs := PrepareNameMatch(d.config.NameMatch, d.config.ArtifactName)
+	errs = packersdk.MultiErrorAppend(errs, nameErrs...)
+	d.nameMatcher = nameMatcher
+
+	// From here on, ArtifactFileTypes holds the extensions to search for, with their leading '.'
+	// Only an unset 'file_type' falls back to vmtx; a blank 'file_types' entry is a mistake, not a request for the default
+	fileTypesSet := len(d.config.ArtifactFileTypes) > 0
+	if d.config.ArtifactFileType != "" && fileTypesSet {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("Please set either 'file_type' or 'file_types', not both."))
+	} else if !fileTypesSet {
+		d.config.ArtifactFileTypes = []string{d.config.ArtifactFileType}
+	}
+	for i, ext := range d.config.ArtifactFileTypes {
+		if fileTypesSet && strings.TrimPrefix(strings.TrimSpace(ext), ".") == "" {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("'file_types' entry %d is blank; list extensions such as \"ova\", or leave 'file_types' out to search for vmtx.", i+1))
 		}
+		d.config.ArtifactFileTypes[i] = normalizeExt(ext)
+	}
+
+	scope, scopeErrs := PrepareScope(d.config.Repositories, d.config.PathPrefix)
+	errs = packersdk.MultiErrorAppend(errs, scopeErrs...)
+	d.config.Repositories, d.config.PathPrefix = scope.Repositories, scope.PathPrefix
+
+	// Without a term for the name search, AQL looks for the candidates, and needs something to keep it from
+	// scanning every file on the server
+	if _, ok := quickSearchTerm(d.config.NameMatch, d.config.ArtifactName); !ok && d.config.Aql == nil && len(d.config.Repositories) == 0 {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("'name_match' '%s' with the 'artifact_name' %q can't use the name search, "+
+			"so every file on the server would be searched; set 'repositories' or use an 'aql' block to narrow it down.", d.config.NameMatch, d.config.ArtifactName))
 	}
-	
-	if d.config.ArtifactoryServer == "" {
-		server := os.Getenv("ARTIFACTORY_SERVER")
-		if server == "" {
-			log.Fatal("---> Please provide the URL to the Artifactory server (ex: https://server.com:8081/artifactory/api).")
+
+	_, windowErrs := PrepareCreatedWindow(d.config.CreatedAfter, d.config.CreatedBefore, d.config.MaxAge)
+	errs = packersdk.MultiErrorAppend(errs, windowErrs...)
+
+	for i := range d.config.PropertyFilters {
+		errs = packersdk.MultiErrorAppend(errs, d.config.PropertyFilters[i].Prepare(i+1)...)
+	}
+
+	if d.config.VersionConstraint != "" {
+		if _, err := version.NewConstraint(d.config.VersionConstraint); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("Invalid 'version_constraint' %q: %s", d.config.VersionConstraint, err))
+		}
+		if d.config.Select == "" {
+			d.config.Select = SelectHighestSemver
 		}
 	}
 
-	if d.config.ArtifactName == "" {
-		log.Fatal("---> Please provide the full or partial artifact name.")
+	if d.config.Select == "" {
+		d.config.Select = SelectLatestCreated
 	}
 
-	if d.config.ArtifactFileType == "" {
-		log.Fatal("---> Please provide the source image's extension type; for example '.vmtx' or 'vmtx'.")
+	if d.config.RevokedProperty == "" {
+		d.config.RevokedProperty = DefaultRevokedProperty
+	}
+	if d.config.DeprecatedProperty == "" {
+		d.config.DeprecatedProperty = DefaultDeprecatedProperty
+	}
+	if !slices.Contains(selectStrategies, d.config.Select) {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("Unknown 'select' value %q; valid values are %s.", d.config.Select, strings.Join(selectStrategies, ", ")))
+	}
+
+	if d.config.Lockfile != "" {
+		if d.config.LockKey == "" {
+			d.config.LockKey = d.config.ArtifactName
+		}
+		if d.config.LockKey == "" {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("Please name this datasource's entry in the lockfile with 'lock_key'."))
+		}
+		if !d.config.LockUpdate {
+			d.config.LockUpdate, _ = strconv.ParseBool(os.Getenv(LockUpdateEnv))
+		}
+	} else if d.config.LockKey != "" || d.config.LockUpdate {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("'lock_key' and 'lock_update' only apply with a 'lockfile'."))
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
 	}
 	return nil
 }
@@ -87,21 +251,82 @@ func BuildPropFilters(kvInput map[string]string) ([]string) {
 	return filterOptions
 }
 
+// NoMatchError describes the search that came back empty so the template author can see which filters to loosen.
+func NoMatchError(artifName, ext string, kvProps []string, err error) error {
+	props := "none"
+	if len(kvProps) > 0 {
+		sorted := append([]string(nil), kvProps...)
+		sort.Strings(sorted)
+		props = strings.Join(sorted, ", ")
+	}
+
+	msg := fmt.Sprintf("No matching artifact was found for artifact_name %q, file_type %q, and property filters [%s]", artifName, ext, props)
+	if err != nil {
+		msg = msg + ": " + strings.TrimSpace(err.Error())
+	}
+	return errors.New(msg)
+}
+
+// ArtifactName is the file name without its extension.
+func ArtifactName(artifact client.Artifact) string {
+	return strings.TrimSuffix(artifact.Name, path.Ext(artifact.Name))
+}
+
+// artifactOutputs describes each artifact for the 'artifacts' output.
+func (d *Datasource) artifactOutputs(artifClient *client.Client, artifacts []client.Artifact) []ArtifactOutput {
+	outputs := []ArtifactOutput{}
+	for _, artifact := range artifacts {
+		created, createdUnix := CreationDate(artifact)
+		lifecycle := ArtifactLifecycle(artifact, d.config.RevokedProperty, d.config.DeprecatedProperty)
+		outputs = append(outputs, ArtifactOutput{
+			Name:        ArtifactName(artifact),
+			FileType:    FileType(artifact),
+			ArtifactUri: artifClient.StorageUrl(artifact.RepoPath()),
+			DownloadUri: artifClient.DownloadUrl(artifact.RepoPath()),
+			Created:     created,
+			CreatedUnix: createdUnix,
+			Modified:    artifact.Modified,
+			CreatedBy:   artifact.CreatedBy,
+			ModifiedBy:  artifact.ModifiedBy,
+			Sha256:      artifact.Sha256,
+			Sha1:        artifact.Sha1,
+			Md5:         artifact.Md5,
+			Size:        artifact.Size,
+			Repo:        artifact.Repo,
+			Path:        artifact.Path,
+			Properties:  PropertyMap(artifact),
+			Revoked:     lifecycle.Revoked,
+			Deprecated:  lifecycle.Deprecated,
+			DeprecationReason: lifecycle.DeprecationReason,
+		})
+	}
+	return outputs
+}
+
+// PropertyMap flattens the artifact's properties, joining several values for the same key with commas.
+func PropertyMap(artifact client.Artifact) map[string]string {
+	props := map[string]string{}
+	for key, values := range artifact.Properties {
+		props[key] = strings.Join(values, ",")
+	}
+	return props
+}
+
 func (d *Datasource) Execute() (cty.Value, error) {
-	var artifName, token, ext, artifactUri, serverApi string
+	var artifName string
 	var kvProperties []string
 
-	// Environment related
-	if d.config.ArtifactoryToken == "" {
-		token = os.Getenv("ARTIFACTORY_TOKEN")
-	} else {
-		token = d.config.ArtifactoryToken
-	}
+	artifClient := client.New(&d.config.ConnectionConfig)
 
-	if d.config.ArtifactoryServer == "" {
-		serverApi = os.Getenv("ARTIFACTORY_SERVER")
-	} else {
-		serverApi = d.config.ArtifactoryServer
+	// A pinned artifact is used as is, without searching
+	if d.config.Lockfile != "" && !d.config.LockUpdate {
+		pinned, found, err := d.lockedArtifact(artifClient)
+		if err != nil {
+			return cty.NullVal(cty.EmptyObject), err
+		}
+		if found {
+			return d.output(artifClient, pinned, []client.Artifact{pinned})
+		}
 	}
 
 	// Artifact Related
@@ -109,9 +334,8 @@ func (d *Datasource) Execute() (cty.Value, error) {
 		artifName = d.config.ArtifactName
 	}
 
-	if d.config.ArtifactFileType != "" {
-		ext = d.config.ArtifactFileType
-	}
+	exts := d.config.ArtifactFileTypes
+	fileTypes := DisplayFileTypes(exts)
 
 	if len(d.config.ArtifactFilter) != 0 {
 		kvProperties = BuildPropFilters(d.config.ArtifactFilter)
@@ -123,23 +347,139 @@ func (d *Datasource) Execute() (cty.Value, error) {
 		kvProperties = append(kvProperties, channelProp)
 	}
 
-	// Search for artifact and return details
-	artifactUri, artifactName, createDate, downloadUri, err := tasks.GetImageDetails(serverApi, token, artifName, ext, kvProperties)
+	// Search for artifacts, then narrow them down the same way regardless of how they were found
+	var artifacts []client.Artifact
+	var query string
+	var err error
+	if d.config.Aql != nil {
+		artifacts, query, err = d.searchAql(artifClient, d.config.Aql)
+	} else if _, ok := quickSearchTerm(d.config.NameMatch, d.config.ArtifactName); !ok {
+		// The quick search can't look for a regular expression, so AQL finds the candidates in 'repositories' instead
+		artifacts, _, err = d.searchAql(artifClient, &AqlConfig{})
+	} else {
+		artifacts, err = d.searchByName(artifClient, exts)
+	}
 	if err != nil {
-		log.Println(err)
-		if artifactUri == "" {
-			log.Println("[ERROR] ----> No Artifact URI was returned; this is likely because no matching artifact was found.")
-		} else if artifactName == "" || createDate == "" || downloadUri == "" {
-			log.Println("[ERROR] ----> An Artifact URI was returned, however an error was encountered retrieving one or more artifact details.")
+		return cty.NullVal(cty.EmptyObject), err
+	}
+
+	// A raw AQL query can reach outside the scope, so the results are always checked
+	scope := d.scope()
+	returned := len(artifacts)
+	artifacts = FilterByScope(scope, artifacts)
+
+	var searchErr error
+	matches := FilterByName(d.nameMatcher, artifacts)
+	matches = FilterByProps(kvProperties, FilterByFileType(exts, matches))
+	matches = FilterByPropertyFilters(d.config.PropertyFilters, matches)
+	if len(matches) == 0 {
+		if d.config.Aql != nil {
+			searchErr = fmt.Errorf("No matching artifact was found for the AQL query %s and file_type %q (%d results returned by the query)", query, fileTypes, returned)
+		} else {
+			searchErr = NoMatchError(artifName, fileTypes, append(kvProperties, PropertyFilterStrings(d.config.PropertyFilters)...), nil)
+		}
+		if scope.IsSet() {
+			searchErr = fmt.Errorf("%s; only %s were searched", searchErr, scope)
+		}
+	}
+
+	// Validated in Configure; max_age is measured from now
+	if window, _ := PrepareCreatedWindow(d.config.CreatedAfter, d.config.CreatedBefore, d.config.MaxAge); searchErr == nil && window.IsSet() {
+		recent := FilterByCreated(window, matches)
+		if len(recent) == 0 {
+			searchErr = StaleError(window, matches)
 		}
+		matches = recent
 	}
-	
+
+	if searchErr == nil && d.config.VersionConstraint != "" {
+		constraints, err := version.NewConstraint(d.config.VersionConstraint)
+		if err != nil {
+			return cty.NullVal(cty.EmptyObject), err
+		}
+		satisfying := FilterByVersion(constraints, d.config.VersionProperty, matches)
+		if len(satisfying) == 0 {
+			searchErr = VersionMismatchError(d.config.VersionConstraint, d.config.VersionProperty, matches)
+		}
+		matches = satisfying
+	}
+
+	// Of the file types that still have matches, the earliest in the list wins
+	if searchErr == nil && len(exts) > 1 {
+		matches = FilterByPreferredFileType(exts, matches)
+		log.Printf("Preferred file type %q of %s", FileType(matches[0]), fileTypes)
+	}
+
+	if searchErr != nil {
+		if !d.config.AllowEmpty {
+			return cty.NullVal(cty.EmptyObject), searchErr
+		}
+		log.Println("[WARN] ----> " + searchErr.Error())
+		log.Println("[WARN] ----> 'allow_empty' is set; returning empty outputs.")
+		output := DatasourceOutput{Properties: map[string]string{}, Artifacts: []ArtifactOutput{}}
+		return hcl2helper.HCL2ValueFromConfig(output, d.OutputSpec()), nil
+	}
+
+	// If more than one artifact matches, 'select' decides which is returned
+	selected, err := SelectArtifact(d.config.Select, d.config.VersionProperty, matches)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+	log.Printf("Found %d matching artifact(s); selected %s (%s)", len(matches), selected.RepoPath(), d.config.Select)
+
+	value, err := d.output(artifClient, selected, matches)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+	if d.config.Lockfile != "" {
+		if err := d.lockArtifact(artifClient, selected); err != nil {
+			return cty.NullVal(cty.EmptyObject), err
+		}
+	}
+	return value, nil
+}
+
+// scope is the search scope from 'repositories' and 'path_prefix', already validated in Configure.
+func (d *Datasource) scope() Scope {
+	return Scope{Repositories: d.config.Repositories, PathPrefix: d.config.PathPrefix}
+}
+
+// output describes the selected artifact, refusing it if it has been revoked; matches fill in 'artifacts'.
+func (d *Datasource) output(artifClient *client.Client, selected client.Artifact, matches []client.Artifact) (cty.Value, error) {
+	lifecycle := ArtifactLifecycle(selected, d.config.RevokedProperty, d.config.DeprecatedProperty)
+	if lifecycle.Revoked {
+		return cty.NullVal(cty.EmptyObject), RevokedError(selected, d.config.RevokedProperty, lifecycle)
+	}
+	if lifecycle.Deprecated {
+		reason := lifecycle.DeprecationReason
+		if reason == "" {
+			reason = "no reason was given"
+		}
+		log.Printf("[WARN] ----> The selected artifact %s is deprecated: %s", selected.RepoPath(), reason)
+	}
+
+	created, createdUnix := CreationDate(selected)
 	output := DatasourceOutput{
-		Name: 	artifactName,
-		Created: 	createDate,
-		ArtifactUri: 	artifactUri,
-		DownloadUri: 	downloadUri,
+		Name: 	ArtifactName(selected),
+		FileType: 	FileType(selected),
+		Created: 	created,
+		CreatedUnix: 	createdUnix,
+		ArtifactUri: 	artifClient.StorageUrl(selected.RepoPath()),
+		DownloadUri: 	artifClient.DownloadUrl(selected.RepoPath()),
+		Sha256: 	selected.Sha256,
+		Sha1: 	selected.Sha1,
+		Md5: 	selected.Md5,
+		Size: 	selected.Size,
+		Modified: 	selected.Modified,
+		CreatedBy: 	selected.CreatedBy,
+		ModifiedBy: 	selected.ModifiedBy,
+		Repo: 	selected.Repo,
+		Path: 	selected.Path,
+		Properties: 	PropertyMap(selected),
+		Deprecated: 	lifecycle.Deprecated,
+		DeprecationReason: 	lifecycle.DeprecationReason,
+		Artifacts: 	d.artifactOutputs(artifClient, matches),
 	}
 
 	return hcl2helper.HCL2ValueFromConfig(output, d.OutputSpec()), nil
-}
\ No newline at end of file
+}
@@ -7,15 +7,127 @@ import (
 	"github.com/zclconf/go-cty/cty"
 )
 
+// FlatAqlConfig is an auto-generated flat version of AqlConfig.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatAqlConfig struct {
+	Query         *string           `mapstructure:"query" required:"false" cty:"query" hcl:"query"`
+	Repositories  []string          `mapstructure:"repositories" required:"false" cty:"repositories" hcl:"repositories"`
+	Path          *string           `mapstructure:"path" required:"false" cty:"path" hcl:"path"`
+	Name          *string           `mapstructure:"name" required:"false" cty:"name" hcl:"name"`
+	Properties    map[string]string `mapstructure:"properties" required:"false" cty:"properties" hcl:"properties"`
+	CreatedAfter  *string           `mapstructure:"created_after" required:"false" cty:"created_after" hcl:"created_after"`
+	CreatedBefore *string           `mapstructure:"created_before" required:"false" cty:"created_before" hcl:"created_before"`
+}
+
+// FlatMapstructure returns a new FlatAqlConfig.
+// FlatAqlConfig is an auto-generated flat version of AqlConfig.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*AqlConfig) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatAqlConfig)
+}
+
+// HCL2Spec returns the hcl spec of a AqlConfig.
+// This spec is used by HCL to read the fields of AqlConfig.
+// The decoded values from this spec will then be applied to a FlatAqlConfig.
+func (*FlatAqlConfig) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"query":          &hcldec.AttrSpec{Name: "query", Type: cty.String, Required: false},
+		"repositories":   &hcldec.AttrSpec{Name: "repositories", Type: cty.List(cty.String), Required: false},
+		"path":           &hcldec.AttrSpec{Name: "path", Type: cty.String, Required: false},
+		"name":           &hcldec.AttrSpec{Name: "name", Type: cty.String, Required: false},
+		"properties":     &hcldec.AttrSpec{Name: "properties", Type: cty.Map(cty.String), Required: false},
+		"created_after":  &hcldec.AttrSpec{Name: "created_after", Type: cty.String, Required: false},
+		"created_before": &hcldec.AttrSpec{Name: "created_before", Type: cty.String, Required: false},
+	}
+	return s
+}
+
+// FlatArtifactOutput is an auto-generated flat version of ArtifactOutput.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatArtifactOutput struct {
+	Name              *string           `mapstructure:"name" cty:"name" hcl:"name"`
+	FileType          *string           `mapstructure:"file_type" cty:"file_type" hcl:"file_type"`
+	ArtifactUri       *string           `mapstructure:"artifact_uri" cty:"artifact_uri" hcl:"artifact_uri"`
+	DownloadUri       *string           `mapstructure:"download_uri" cty:"download_uri" hcl:"download_uri"`
+	Created           *string           `mapstructure:"creation_date" cty:"creation_date" hcl:"creation_date"`
+	CreatedUnix       *int64            `mapstructure:"creation_timestamp" cty:"creation_timestamp" hcl:"creation_timestamp"`
+	Modified          *string           `mapstructure:"last_modified" cty:"last_modified" hcl:"last_modified"`
+	CreatedBy         *string           `mapstructure:"created_by" cty:"created_by" hcl:"created_by"`
+	ModifiedBy        *string           `mapstructure:"modified_by" cty:"modified_by" hcl:"modified_by"`
+	Sha256            *string           `mapstructure:"sha256" cty:"sha256" hcl:"sha256"`
+	Sha1              *string           `mapstructure:"sha1" cty:"sha1" hcl:"sha1"`
+	Md5               *string           `mapstructure:"md5" cty:"md5" hcl:"md5"`
+	Size              *int64            `mapstructure:"size" cty:"size" hcl:"size"`
+	Repo              *string           `mapstructure:"repo" cty:"repo" hcl:"repo"`
+	Path              *string           `mapstructure:"path" cty:"path" hcl:"path"`
+	Properties        map[string]string `mapstructure:"properties" cty:"properties" hcl:"properties"`
+	Revoked           *bool             `mapstructure:"revoked" cty:"revoked" hcl:"revoked"`
+	Deprecated        *bool             `mapstructure:"deprecated" cty:"deprecated" hcl:"deprecated"`
+	DeprecationReason *string           `mapstructure:"deprecation_reason" cty:"deprecation_reason" hcl:"deprecation_reason"`
+}
+
+// FlatMapstructure returns a new FlatArtifactOutput.
+// FlatArtifactOutput is an auto-generated flat version of ArtifactOutput.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*ArtifactOutput) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatArtifactOutput)
+}
+
+// HCL2Spec returns the hcl spec of a ArtifactOutput.
+// This spec is used by HCL to read the fields of ArtifactOutput.
+// The decoded values from this spec will then be applied to a FlatArtifactOutput.
+func (*FlatArtifactOutput) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"name":               &hcldec.AttrSpec{Name: "name", Type: cty.String, Required: false},
+		"file_type":          &hcldec.AttrSpec{Name: "file_type", Type: cty.String, Required: false},
+		"artifact_uri":       &hcldec.AttrSpec{Name: "artifact_uri", Type: cty.String, Required: false},
+		"download_uri":       &hcldec.AttrSpec{Name: "download_uri", Type: cty.String, Required: false},
+		"creation_date":      &hcldec.AttrSpec{Name: "creation_date", Type: cty.String, Required: false},
+		"creation_timestamp": &hcldec.AttrSpec{Name: "creation_timestamp", Type: cty.Number, Required: false},
+		"last_modified":      &hcldec.AttrSpec{Name: "last_modified", Type: cty.String, Required: false},
+		"created_by":         &hcldec.AttrSpec{Name: "created_by", Type: cty.String, Required: false},
+		"modified_by":        &hcldec.AttrSpec{Name: "modified_by", Type: cty.String, Required: false},
+		"sha256":             &hcldec.AttrSpec{Name: "sha256", Type: cty.String, Required: false},
+		"sha1":               &hcldec.AttrSpec{Name: "sha1", Type: cty.String, Required: false},
+		"md5":                &hcldec.AttrSpec{Name: "md5", Type: cty.String, Required: false},
+		"size":               &hcldec.AttrSpec{Name: "size", Type: cty.Number, Required: false},
+		"repo":               &hcldec.AttrSpec{Name: "repo", Type: cty.String, Required: false},
+		"path":               &hcldec.AttrSpec{Name: "path", Type: cty.String, Required: false},
+		"properties":         &hcldec.AttrSpec{Name: "properties", Type: cty.Map(cty.String), Required: false},
+		"revoked":            &hcldec.AttrSpec{Name: "revoked", Type: cty.Bool, Required: false},
+		"deprecated":         &hcldec.AttrSpec{Name: "deprecated", Type: cty.Bool, Required: false},
+		"deprecation_reason": &hcldec.AttrSpec{Name: "deprecation_reason", Type: cty.String, Required: false},
+	}
+	return s
+}
+
 // FlatConfig is an auto-generated flat version of Config.
 // Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
 type FlatConfig struct {
-	ArtifactoryToken  *string           `mapstructure:"artifactory_token" required:"true" cty:"artifactory_token" hcl:"artifactory_token"`
-	ArtifactoryServer *string           `mapstructure:"artifactory_server" required:"true" cty:"artifactory_server" hcl:"artifactory_server"`
-	ArtifactName      *string           `mapstructure:"artifact_name" required:"true" cty:"artifact_name" hcl:"artifact_name"`
-	ArtifactFileType  *string           `mapstructure:"file_type" required:"true" cty:"file_type" hcl:"file_type"`
-	ArtifactChannel   *string           `mapstructure:"channel" required:"false" cty:"channel" hcl:"channel"`
-	ArtifactFilter    map[string]string `mapstructure:"filter" required:"false" cty:"filter" hcl:"filter"`
+	ArtifactoryToken   *string              `mapstructure:"artifactory_token" required:"true" cty:"artifactory_token" hcl:"artifactory_token"`
+	ArtifactoryServer  *string              `mapstructure:"artifactory_server" required:"true" cty:"artifactory_server" hcl:"artifactory_server"`
+	ArtifactName       *string              `mapstructure:"artifact_name" required:"true" cty:"artifact_name" hcl:"artifact_name"`
+	NameMatch          *string              `mapstructure:"name_match" required:"false" cty:"name_match" hcl:"name_match"`
+	ArtifactFileType   *string              `mapstructure:"file_type" required:"false" cty:"file_type" hcl:"file_type"`
+	ArtifactFileTypes  []string             `mapstructure:"file_types" required:"false" cty:"file_types" hcl:"file_types"`
+	Repositories       []string             `mapstructure:"repositories" required:"false" cty:"repositories" hcl:"repositories"`
+	PathPrefix         *string              `mapstructure:"path_prefix" required:"false" cty:"path_prefix" hcl:"path_prefix"`
+	ArtifactChannel    *string              `mapstructure:"channel" required:"false" cty:"channel" hcl:"channel"`
+	RevokedProperty    *string              `mapstructure:"revoked_property" required:"false" cty:"revoked_property" hcl:"revoked_property"`
+	DeprecatedProperty *string              `mapstructure:"deprecated_property" required:"false" cty:"deprecated_property" hcl:"deprecated_property"`
+	ArtifactFilter     map[string]string    `mapstructure:"filter" required:"false" cty:"filter" hcl:"filter"`
+	PropertyFilters    []FlatPropertyFilter `mapstructure:"property_filter" required:"false" cty:"property_filter" hcl:"property_filter"`
+	CreatedAfter       *string              `mapstructure:"created_after" required:"false" cty:"created_after" hcl:"created_after"`
+	CreatedBefore      *string              `mapstructure:"created_before" required:"false" cty:"created_before" hcl:"created_before"`
+	MaxAge             *string              `mapstructure:"max_age" required:"false" cty:"max_age" hcl:"max_age"`
+	AllowEmpty         *bool                `mapstructure:"allow_empty" required:"false" cty:"allow_empty" hcl:"allow_empty"`
+	Select             *string              `mapstructure:"select" required:"false" cty:"select" hcl:"select"`
+	VersionProperty    *string              `mapstructure:"version_property" required:"false" cty:"version_property" hcl:"version_property"`
+	VersionConstraint  *string              `mapstructure:"version_constraint" required:"false" cty:"version_constraint" hcl:"version_constraint"`
+	Aql                *FlatAqlConfig       `mapstructure:"aql" required:"false" cty:"aql" hcl:"aql"`
+	Lockfile           *string              `mapstructure:"lockfile" required:"false" cty:"lockfile" hcl:"lockfile"`
+	LockKey            *string              `mapstructure:"lock_key" required:"false" cty:"lock_key" hcl:"lock_key"`
+	LockUpdate         *bool                `mapstructure:"lock_update" required:"false" cty:"lock_update" hcl:"lock_update"`
 }
 
 // FlatMapstructure returns a new FlatConfig.
@@ -30,12 +142,30 @@ func (*Config) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec }
 // The decoded values from this spec will then be applied to a FlatConfig.
 func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
 	s := map[string]hcldec.Spec{
-		"artifactory_token":  &hcldec.AttrSpec{Name: "artifactory_token", Type: cty.String, Required: false},
-		"artifactory_server": &hcldec.AttrSpec{Name: "artifactory_server", Type: cty.String, Required: false},
-		"artifact_name":      &hcldec.AttrSpec{Name: "artifact_name", Type: cty.String, Required: false},
-		"file_type":          &hcldec.AttrSpec{Name: "file_type", Type: cty.String, Required: false},
-		"channel":            &hcldec.AttrSpec{Name: "channel", Type: cty.String, Required: false},
-		"filter":             &hcldec.AttrSpec{Name: "filter", Type: cty.Map(cty.String), Required: false},
+		"artifactory_token":   &hcldec.AttrSpec{Name: "artifactory_token", Type: cty.String, Required: false},
+		"artifactory_server":  &hcldec.AttrSpec{Name: "artifactory_server", Type: cty.String, Required: false},
+		"artifact_name":       &hcldec.AttrSpec{Name: "artifact_name", Type: cty.String, Required: false},
+		"name_match":          &hcldec.AttrSpec{Name: "name_match", Type: cty.String, Required: false},
+		"file_type":           &hcldec.AttrSpec{Name: "file_type", Type: cty.String, Required: false},
+		"file_types":          &hcldec.AttrSpec{Name: "file_types", Type: cty.List(cty.String), Required: false},
+		"repositories":        &hcldec.AttrSpec{Name: "repositories", Type: cty.List(cty.String), Required: false},
+		"path_prefix":         &hcldec.AttrSpec{Name: "path_prefix", Type: cty.String, Required: false},
+		"channel":             &hcldec.AttrSpec{Name: "channel", Type: cty.String, Required: false},
+		"revoked_property":    &hcldec.AttrSpec{Name: "revoked_property", Type: cty.String, Required: false},
+		"deprecated_property": &hcldec.AttrSpec{Name: "deprecated_property", Type: cty.String, Required: false},
+		"filter":              &hcldec.AttrSpec{Name: "filter", Type: cty.Map(cty.String), Required: false},
+		"property_filter":     &hcldec.BlockListSpec{TypeName: "property_filter", Nested: hcldec.ObjectSpec((*FlatPropertyFilter)(nil).HCL2Spec())},
+		"created_after":       &hcldec.AttrSpec{Name: "created_after", Type: cty.String, Required: false},
+		"created_before":      &hcldec.AttrSpec{Name: "created_before", Type: cty.String, Required: false},
+		"max_age":             &hcldec.AttrSpec{Name: "max_age", Type: cty.String, Required: false},
+		"allow_empty":         &hcldec.AttrSpec{Name: "allow_empty", Type: cty.Bool, Required: false},
+		"select":              &hcldec.AttrSpec{Name: "select", Type: cty.String, Required: false},
+		"version_property":    &hcldec.AttrSpec{Name: "version_property", Type: cty.String, Required: false},
+		"version_constraint":  &hcldec.AttrSpec{Name: "version_constraint", Type: cty.String, Required: false},
+		"aql":                 &hcldec.BlockSpec{TypeName: "aql", Nested: hcldec.ObjectSpec((*FlatAqlConfig)(nil).HCL2Spec())},
+		"lockfile":            &hcldec.AttrSpec{Name: "lockfile", Type: cty.String, Required: false},
+		"lock_key":            &hcldec.AttrSpec{Name: "lock_key", Type: cty.String, Required: false},
+		"lock_update":         &hcldec.AttrSpec{Name: "lock_update", Type: cty.Bool, Required: false},
 	}
 	return s
 }
@@ -43,10 +173,26 @@ func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
 // FlatDatasourceOutput is an auto-generated flat version of DatasourceOutput.
 // Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
 type FlatDatasourceOutput struct {
-	Name        *string `mapstructure:"name" cty:"name" hcl:"name"`
-	Created     *string `mapstructure:"creation_date" cty:"creation_date" hcl:"creation_date"`
-	ArtifactUri *string `mapstructure:"artifact_uri" cty:"artifact_uri" hcl:"artifact_uri"`
-	DownloadUri *string `mapstructure:"download_uri" cty:"download_uri" hcl:"download_uri"`
+	Name              *string              `mapstructure:"name" cty:"name" hcl:"name"`
+	FileType          *string              `mapstructure:"file_type" cty:"file_type" hcl:"file_type"`
+	Created           *string              `mapstructure:"creation_date" cty:"creation_date" hcl:"creation_date"`
+	CreatedUnix       *int64               `mapstructure:"creation_timestamp" cty:"creation_timestamp" hcl:"creation_timestamp"`
+	ArtifactUri       *string              `mapstructure:"artifact_uri" cty:"artifact_uri" hcl:"artifact_uri"`
+	DownloadUri       *string              `mapstructure:"download_uri" cty:"download_uri" hcl:"download_uri"`
+	Sha256            *string              `mapstructure:"sha256" cty:"sha256" hcl:"sha256"`
+	Sha1              *string              `mapstructure:"sha1" cty:"sha1" hcl:"sha1"`
+	Md5               *string              `mapstructure:"md5" cty:"md5" hcl:"md5"`
+	Size              *int64               `mapstructure:"size" cty:"size" hcl:"size"`
+	Modified          *string              `mapstructure:"last_modified" cty:"last_modified" hcl:"last_modified"`
+	CreatedBy         *string              `mapstructure:"created_by" cty:"created_by" hcl:"created_by"`
+	ModifiedBy        *string              `mapstructure:"modified_by" cty:"modified_by" hcl:"modified_by"`
+	Repo              *string              `mapstructure:"repo" cty:"repo" hcl:"repo"`
+	Path              *string              `mapstructure:"path" cty:"path" hcl:"path"`
+	Properties        map[string]string    `mapstructure:"properties" cty:"properties" hcl:"properties"`
+	Revoked           *bool                `mapstructure:"revoked" cty:"revoked" hcl:"revoked"`
+	Deprecated        *bool                `mapstructure:"deprecated" cty:"deprecated" hcl:"deprecated"`
+	DeprecationReason *string              `mapstructure:"deprecation_reason" cty:"deprecation_reason" hcl:"deprecation_reason"`
+	Artifacts         []FlatArtifactOutput `mapstructure:"artifacts" cty:"artifacts" hcl:"artifacts"`
 }
 
 // FlatMapstructure returns a new FlatDatasourceOutput.
@@ -61,10 +207,53 @@ func (*DatasourceOutput) FlatMapstructure() interface{ HCL2Spec() map[string]hcl
 // The decoded values from this spec will then be applied to a FlatDatasourceOutput.
 func (*FlatDatasourceOutput) HCL2Spec() map[string]hcldec.Spec {
 	s := map[string]hcldec.Spec{
-		"name":          &hcldec.AttrSpec{Name: "name", Type: cty.String, Required: false},
-		"creation_date": &hcldec.AttrSpec{Name: "creation_date", Type: cty.String, Required: false},
-		"artifact_uri":  &hcldec.AttrSpec{Name: "artifact_uri", Type: cty.String, Required: false},
-		"download_uri":  &hcldec.AttrSpec{Name: "download_uri", Type: cty.String, Required: false},
+		"name":               &hcldec.AttrSpec{Name: "name", Type: cty.String, Required: false},
+		"file_type":          &hcldec.AttrSpec{Name: "file_type", Type: cty.String, Required: false},
+		"creation_date":      &hcldec.AttrSpec{Name: "creation_date", Type: cty.String, Required: false},
+		"creation_timestamp": &hcldec.AttrSpec{Name: "creation_timestamp", Type: cty.Number, Required: false},
+		"artifact_uri":       &hcldec.AttrSpec{Name: "artifact_uri", Type: cty.String, Required: false},
+		"download_uri":       &hcldec.AttrSpec{Name: "download_uri", Type: cty.String, Required: false},
+		"sha256":             &hcldec.AttrSpec{Name: "sha256", Type: cty.String, Required: false},
+		"sha1":               &hcldec.AttrSpec{Name: "sha1", Type: cty.String, Required: false},
+		"md5":                &hcldec.AttrSpec{Name: "md5", Type: cty.String, Required: false},
+		"size":               &hcldec.AttrSpec{Name: "size", Type: cty.Number, Required: false},
+		"last_modified":      &hcldec.AttrSpec{Name: "last_modified", Type: cty.String, Required: false},
+		"created_by":         &hcldec.AttrSpec{Name: "created_by", Type: cty.String, Required: false},
+		"modified_by":        &hcldec.AttrSpec{Name: "modified_by", Type: cty.String, Required: false},
+		"repo":               &hcldec.AttrSpec{Name: "repo", Type: cty.String, Required: false},
+		"path":               &hcldec.AttrSpec{Name: "path", Type: cty.String, Required: false},
+		"properties":         &hcldec.AttrSpec{Name: "properties", Type: cty.Map(cty.String), Required: false},
+		"revoked":            &hcldec.AttrSpec{Name: "revoked", Type: cty.Bool, Required: false},
+		"deprecated":         &hcldec.AttrSpec{Name: "deprecated", Type: cty.Bool, Required: false},
+		"deprecation_reason": &hcldec.AttrSpec{Name: "deprecation_reason", Type: cty.String, Required: false},
+		"artifacts":          &hcldec.BlockListSpec{TypeName: "artifacts", Nested: hcldec.ObjectSpec((*FlatArtifactOutput)(nil).HCL2Spec())},
+	}
+	return s
+}
+
+// FlatPropertyFilter is an auto-generated flat version of PropertyFilter.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatPropertyFilter struct {
+	Key      *string  `mapstructure:"key" required:"true" cty:"key" hcl:"key"`
+	Operator *string  `mapstructure:"operator" required:"false" cty:"operator" hcl:"operator"`
+	Values   []string `mapstructure:"values" required:"false" cty:"values" hcl:"values"`
+}
+
+// FlatMapstructure returns a new FlatPropertyFilter.
+// FlatPropertyFilter is an auto-generated flat version of PropertyFilter.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*PropertyFilter) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatPropertyFilter)
+}
+
+// HCL2Spec returns the hcl spec of a PropertyFilter.
+// This spec is used by HCL to read the fields of PropertyFilter.
+// The decoded values from this spec will then be applied to a FlatPropertyFilter.
+func (*FlatPropertyFilter) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"key":      &hcldec.AttrSpec{Name: "key", Type: cty.String, Required: false},
+		"operator": &hcldec.AttrSpec{Name: "operator", Type: cty.String, Required: false},
+		"values":   &hcldec.AttrSpec{Name: "values", Type: cty.List(cty.String), Required: false},
 	}
 	return s
 }
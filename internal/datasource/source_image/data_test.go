@@ -0,0 +1,523 @@
+package artifactImage
+
+import (
+	"errors"
+	"path"
+	"slices"
+	"strings"
+	"testing"
+
+	"packer-plugin-artifactory/internal/testutil/fakeartifactory"
+)
+
+func TestNoMatchError(t *testing.T) {
+	tests := []struct {
+		name    string
+		ext     string
+		kvProps []string
+		err     error
+		want    string
+	}{
+		{
+			name: "win22",
+			ext:  "ova",
+			want: `No matching artifact was found for artifact_name "win22", file_type "ova", and property filters [none]`,
+		},
+		{
+			name:    "win22",
+			ext:     "vmtx",
+			kvProps: []string{"release=stable", "channel=prod"},
+			err:     errors.New("No results returned\n"),
+			want:    `No matching artifact was found for artifact_name "win22", file_type "vmtx", and property filters [channel=prod, release=stable]: No results returned`,
+		},
+	}
+
+	for _, tt := range tests {
+		if got := NoMatchError(tt.name, tt.ext, tt.kvProps, tt.err).Error(); got != tt.want {
+			t.Errorf("NoMatchError() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestDatasourceExecute(t *testing.T) {
+	server := fakeartifactory.New(t)
+	server.AddArtifact("/images/win22/win22-old.ova", []byte("old"), map[string]string{"release": "stable"})
+	server.AddArtifact("/images/win22/win22-new.ova", []byte("new"), map[string]string{"release": "testing", "quarantined": "true"})
+	server.AddArtifact("/images/win22/win22.vmtx", []byte("vmtx"), nil)
+	server.AddArtifact("/images/rhel9/rhel9.ova", []byte("rhel"), nil)
+	server.AddArtifact("/images/rhel8/rhel8-8.9.ova", []byte("8.9"), map[string]string{"version": "8.9.0"})
+	server.AddArtifact("/images/rhel8/rhel8-8.10.ova", []byte("8.10"), map[string]string{"version": "8.10.0"})
+	server.AddArtifact("/images/rhel8/rhel8-9.0-beta.ova", []byte("9.0"), map[string]string{"version": "9.0.0-beta"})
+
+	tests := []struct {
+		name      string
+		config    map[string]interface{}
+		wantName  string
+		wantFile  string
+		wantErr   string
+		wantEmpty bool
+	}{
+		{
+			name:     "single match by file type",
+			config:   map[string]interface{}{"artifact_name": "win22", "file_type": "vmtx"},
+			wantName: "win22",
+			wantFile: "win22.vmtx",
+		},
+		{
+			name:     "latest of several matches",
+			config:   map[string]interface{}{"artifact_name": "win22", "file_type": "ova"},
+			wantName: "win22-new",
+			wantFile: "win22-new.ova",
+		},
+		{
+			name:     "property filter",
+			config:   map[string]interface{}{"artifact_name": "win22", "file_type": "ova", "filter": map[string]string{"release": "stable"}},
+			wantName: "win22-old",
+			wantFile: "win22-old.ova",
+		},
+		{
+			name:    "no match",
+			config:  map[string]interface{}{"artifact_name": "win10", "file_type": "ova"},
+			wantErr: `No matching artifact was found for artifact_name "win10"`,
+		},
+		{
+			name:    "no match for file type",
+			config:  map[string]interface{}{"artifact_name": "rhel9", "file_type": "ovf"},
+			wantErr: `No matching artifact was found for artifact_name "rhel9"`,
+		},
+		{
+			name:     "aql structured",
+			config:   map[string]interface{}{"file_type": "ova", "aql": map[string]interface{}{"repositories": []string{"images", "scratch"}, "name": "win22-*", "properties": map[string]string{"release": "stable"}}},
+			wantName: "win22-old",
+			wantFile: "win22-old.ova",
+		},
+		{
+			name:     "aql structured with artifact name",
+			config:   map[string]interface{}{"artifact_name": "rhel", "file_type": "ova", "aql": map[string]interface{}{"path": "rhel9"}},
+			wantName: "rhel9",
+			wantFile: "rhel9.ova",
+		},
+		{
+			name:     "aql raw",
+			config:   map[string]interface{}{"file_type": "ova", "aql": map[string]interface{}{"query": `items.find({"$or":[{"@release":"stable"},{"@release":"testing"}]})`}},
+			wantName: "win22-new",
+			wantFile: "win22-new.ova",
+		},
+		{
+			name:    "aql no match for file type",
+			config:  map[string]interface{}{"file_type": "ovf", "aql": map[string]interface{}{"path": "win22"}},
+			wantErr: `No matching artifact was found for the AQL query`,
+		},
+		{
+			name:     "select oldest",
+			config:   map[string]interface{}{"artifact_name": "win22", "file_type": "ova", "select": "oldest"},
+			wantName: "win22-old",
+			wantFile: "win22-old.ova",
+		},
+		{
+			name:    "select unique",
+			config:  map[string]interface{}{"artifact_name": "win22", "file_type": "ova", "select": "unique"},
+			wantErr: "2 artifacts matched",
+		},
+		{
+			name:     "version constraint on property",
+			config:   map[string]interface{}{"artifact_name": "rhel8", "file_type": "ova", "version_property": "version", "version_constraint": ">= 8.0, < 9.0"},
+			wantName: "rhel8-8.10",
+			wantFile: "rhel8-8.10.ova",
+		},
+		{
+			name:     "version constraint on file name",
+			config:   map[string]interface{}{"artifact_name": "rhel8", "file_type": "ova", "version_constraint": "~> 8.9"},
+			wantName: "rhel8-8.10",
+			wantFile: "rhel8-8.10.ova",
+		},
+		{
+			name:    "version constraint not satisfied",
+			config:  map[string]interface{}{"artifact_name": "rhel8", "file_type": "ova", "version_property": "version", "version_constraint": ">= 10"},
+			wantErr: `satisfy version_constraint ">= 10"`,
+		},
+		{
+			name: "property filter excludes quarantined",
+			config: map[string]interface{}{"artifact_name": "win22", "file_type": "ova", "property_filter": []map[string]interface{}{
+				{"key": "release", "values": []string{"stable", "testing"}},
+				{"key": "quarantined", "operator": "absent"},
+			}},
+			wantName: "win22-old",
+			wantFile: "win22-old.ova",
+		},
+		{
+			name: "aql property filter",
+			config: map[string]interface{}{"file_type": "ova", "aql": map[string]interface{}{"repositories": []string{"images"}}, "property_filter": []map[string]interface{}{
+				{"key": "release", "operator": "match", "values": []string{"st*"}},
+			}},
+			wantName: "win22-old",
+			wantFile: "win22-old.ova",
+		},
+		{
+			name: "property filter no match",
+			config: map[string]interface{}{"artifact_name": "win22", "file_type": "ova", "property_filter": []map[string]interface{}{
+				{"key": "release", "operator": "regex", "values": []string{"^prod"}},
+			}},
+			wantErr: `property filters [release regex [^prod]]`,
+		},
+		{
+			name:     "file type defaults to vmtx",
+			config:   map[string]interface{}{"artifact_name": "win22"},
+			wantName: "win22",
+			wantFile: "win22.vmtx",
+		},
+		{
+			name:     "first preferred file type with a match",
+			config:   map[string]interface{}{"artifact_name": "win22", "file_types": []string{"ovf", ".vmtx", "ova"}},
+			wantName: "win22",
+			wantFile: "win22.vmtx",
+		},
+		{
+			name:     "preferred file type after filtering",
+			config:   map[string]interface{}{"artifact_name": "win22", "file_types": []string{"vmtx", "ova"}, "filter": map[string]string{"release": "stable"}},
+			wantName: "win22-old",
+			wantFile: "win22-old.ova",
+		},
+		{
+			name:    "no match for any file type",
+			config:  map[string]interface{}{"artifact_name": "rhel9", "file_types": []string{"ovf", "vmtx"}},
+			wantErr: `file_type "ovf, vmtx"`,
+		},
+		{
+			name:      "no match allowed",
+			config:    map[string]interface{}{"artifact_name": "win10", "file_type": "ova", "allow_empty": true},
+			wantEmpty: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config["artifactory_token"] = fakeartifactory.Token
+			tt.config["artifactory_server"] = server.ApiUrl()
+
+			d := &Datasource{}
+			if err := d.Configure(tt.config); err != nil {
+				t.Fatalf("Configure() error = %s", err)
+			}
+			value, err := d.Execute()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Execute() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Execute() error = %s", err)
+			}
+
+			name := value.GetAttr("name").AsString()
+			if tt.wantEmpty {
+				if name != "" {
+					t.Errorf("name = %q, want empty", name)
+				}
+				return
+			}
+			if name != tt.wantName {
+				t.Errorf("name = %q, want %q", name, tt.wantName)
+			}
+			if uri := value.GetAttr("download_uri").AsString(); !strings.HasSuffix(uri, "/"+tt.wantFile) {
+				t.Errorf("download_uri = %q", uri)
+			}
+			if fileType := value.GetAttr("file_type").AsString(); fileType != strings.TrimPrefix(path.Ext(tt.wantFile), ".") {
+				t.Errorf("file_type = %q, want the extension of %s", fileType, tt.wantFile)
+			}
+		})
+	}
+}
+
+func TestAqlConfigPrepare(t *testing.T) {
+	tests := []struct {
+		name      string
+		aql       AqlConfig
+		artifName string
+		channel   string
+		wantErrs  int
+	}{
+		{name: "raw query", aql: AqlConfig{Query: `items.find({"repo":"images"})`}},
+		{name: "structured", aql: AqlConfig{Repositories: []string{"images"}, CreatedAfter: "2024-01-31T00:00:00Z"}},
+		{name: "artifact name only", artifName: "win22"},
+		{name: "empty", wantErrs: 1},
+		{name: "raw and structured", aql: AqlConfig{Query: `items.find({"repo":"images"})`, Path: "win22"}, wantErrs: 1},
+		{name: "raw with channel", aql: AqlConfig{Query: `items.find({"repo":"images"})`}, channel: "prod", wantErrs: 1},
+		{name: "not a find query", aql: AqlConfig{Query: `builds.find()`}, wantErrs: 1},
+		{name: "bad dates", aql: AqlConfig{Name: "*.ova", CreatedAfter: "yesterday", CreatedBefore: "2024-01-31"}, wantErrs: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if errs := tt.aql.Prepare(tt.artifName, nil, tt.channel); len(errs) != tt.wantErrs {
+				t.Errorf("Prepare() = %v, want %d errors", errs, tt.wantErrs)
+			}
+		})
+	}
+}
+
+func TestBuildAqlQuery(t *testing.T) {
+	aql := &AqlConfig{
+		Repositories: []string{"images", "scratch"},
+		Path:         "/windows/",
+		Properties:   map[string]string{"release": "stable"},
+		CreatedAfter: "2024-01-31T00:00:00Z",
+	}
+	want := `items.find({"$and":[{"type":"file"},{"$or":[{"repo":"images"},{"repo":"scratch"}]},{"path":{"$match":"windows"}},` +
+		`{"name":{"$match":"*win22*"}},{"@channel":"prod"},{"@release":"stable"},{"created":{"$gt":"2024-01-31T00:00:00Z"}}]})`
+
+	got, err := BuildAqlQuery(aql, AqlSearch{ArtifactName: "win22", Properties: map[string]string{"channel": "prod"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("BuildAqlQuery() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWithAqlInclude(t *testing.T) {
+	all := `"repo","path","name","type","size","created","created_by","modified","modified_by","updated","sha256","actual_sha1","actual_md5","property.*"`
+	tests := []struct {
+		name, query, want string
+	}{
+		{"no include", `items.find({"name":"a(1).ova"}).limit(1)`, `items.find({"name":"a(1).ova"}).include(` + all + `).limit(1)`},
+		{"own include", `items.find({"repo":"images"}).include("name", "repo","stat.downloads").sort({"$desc":["created"]})`,
+			`items.find({"repo":"images"}).include("name","repo","stat.downloads","path","type","size","created","created_by","modified",` +
+				`"modified_by","updated","sha256","actual_sha1","actual_md5","property.*").sort({"$desc":["created"]})`},
+		{"complete include", `items.find().include(` + all + `)`, `items.find().include(` + all + `)`},
+	}
+	for _, tt := range tests {
+		if got := WithAqlInclude(tt.query); got != tt.want {
+			t.Errorf("%s: WithAqlInclude() =\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDatasourceExecute_Artifacts(t *testing.T) {
+	server := fakeartifactory.New(t)
+	server.AddArtifact("/images/win22/win22-old.ova", []byte("old"), map[string]string{"release": "stable"})
+	server.AddArtifact("/images/win22/win22-new.ova", []byte("newer"), map[string]string{"release": "stable"})
+	server.AddArtifact("/images/win22.ova", []byte("root"), map[string]string{"release": "testing"})
+	item, _ := server.Artifact("/images/win22/win22-new.ova")
+	item.Properties["os"] = []string{"windows", "server"}
+
+	tests := []struct {
+		name   string
+		config map[string]interface{}
+		want   []string
+	}{
+		{
+			name:   "name search",
+			config: map[string]interface{}{"artifact_name": "win22", "file_type": "ova"},
+			want:   []string{"/images/win22.ova", "/images/win22/win22-new.ova", "/images/win22/win22-old.ova"},
+		},
+		{
+			name:   "name search with filter",
+			config: map[string]interface{}{"artifact_name": "win22", "file_type": "ova", "filter": map[string]string{"release": "stable"}},
+			want:   []string{"/images/win22/win22-new.ova", "/images/win22/win22-old.ova"},
+		},
+		{
+			name:   "aql",
+			config: map[string]interface{}{"file_type": "ova", "aql": map[string]interface{}{"path": "win22"}},
+			want:   []string{"/images/win22/win22-new.ova", "/images/win22/win22-old.ova"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config["artifactory_token"] = fakeartifactory.Token
+			tt.config["artifactory_server"] = server.ApiUrl()
+
+			d := &Datasource{}
+			if err := d.Configure(tt.config); err != nil {
+				t.Fatalf("Configure() error = %s", err)
+			}
+			value, err := d.Execute()
+			if err != nil {
+				t.Fatalf("Execute() error = %s", err)
+			}
+
+			artifacts := value.GetAttr("artifacts").AsValueSlice()
+			if len(artifacts) != len(tt.want) {
+				t.Fatalf("got %d artifacts, want %d", len(artifacts), len(tt.want))
+			}
+			for i, artifact := range artifacts {
+				if uri := artifact.GetAttr("download_uri").AsString(); uri != server.DownloadUrl(tt.want[i]) {
+					t.Errorf("artifacts[%d].download_uri = %q, want %q", i, uri, server.DownloadUrl(tt.want[i]))
+				}
+			}
+			if first := artifacts[0].GetAttr("artifact_uri").AsString(); first != value.GetAttr("artifact_uri").AsString() {
+				t.Errorf("artifacts[0] = %q, but the selected artifact is %q", first, value.GetAttr("artifact_uri").AsString())
+			}
+
+			for _, artifact := range artifacts {
+				if artifact.GetAttr("name").AsString() != "win22-new" {
+					continue
+				}
+				size, _ := artifact.GetAttr("size").AsBigFloat().Int64()
+				if size != 5 || artifact.GetAttr("repo").AsString() != "images" || artifact.GetAttr("path").AsString() != "win22" {
+					t.Errorf("unexpected size, repo or path: %#v", artifact)
+				}
+				if artifact.GetAttr("creation_date").AsString() == "" || artifact.GetAttr("last_modified").AsString() == "" {
+					t.Errorf("missing dates: %#v", artifact)
+				}
+				props := artifact.GetAttr("properties").AsValueMap()
+				if props["release"].AsString() != "stable" || props["os"].AsString() != "windows,server" {
+					t.Errorf("unexpected properties: %#v", props)
+				}
+			}
+		})
+	}
+}
+
+func TestDatasourceConfigure_Select(t *testing.T) {
+	tests := []struct {
+		selectBy string
+		want     string
+		wantErr  bool
+	}{
+		{"", SelectLatestCreated, false},
+		{"highest_semver", SelectHighestSemver, false},
+		{"newest", "", true},
+	}
+
+	for _, tt := range tests {
+		d := &Datasource{}
+		err := d.Configure(map[string]interface{}{
+			"artifactory_token":  "token",
+			"artifactory_server": "https://server.com/artifactory/api",
+			"artifact_name":      "win22",
+			"file_type":          "ova",
+			"select":             tt.selectBy,
+		})
+		if tt.wantErr {
+			if err == nil || !strings.Contains(err.Error(), "'select'") {
+				t.Errorf("Configure(select = %q) error = %v, want an unknown 'select' error", tt.selectBy, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Configure(select = %q) error = %s", tt.selectBy, err)
+		}
+		if d.config.Select != tt.want {
+			t.Errorf("select = %q, want %q", d.config.Select, tt.want)
+		}
+	}
+}
+
+func TestDatasourceConfigure_FileTypes(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		want    []string
+		wantErr string
+	}{
+		{name: "default", config: map[string]interface{}{}, want: []string{".vmtx"}},
+		{name: "file_type", config: map[string]interface{}{"file_type": "ova"}, want: []string{".ova"}},
+		{name: "file_types", config: map[string]interface{}{"file_types": []string{"ova", ".ovf"}}, want: []string{".ova", ".ovf"}},
+		{name: "both", config: map[string]interface{}{"file_type": "ova", "file_types": []string{"ovf"}}, wantErr: "either 'file_type' or 'file_types'"},
+		{name: "blank entry", config: map[string]interface{}{"file_types": []string{"ova", ""}}, wantErr: "'file_types' entry 2 is blank"},
+		{name: "only a blank entry", config: map[string]interface{}{"file_types": []string{""}}, wantErr: "'file_types' entry 1 is blank"},
+		{name: "only whitespace", config: map[string]interface{}{"file_types": []string{" . "}}, wantErr: "'file_types' entry 1 is blank"},
+	}
+
+	for _, tt := range tests {
+		tt.config["artifactory_token"] = "token"
+		tt.config["artifactory_server"] = "https://server.com/artifactory/api"
+		tt.config["artifact_name"] = "win22"
+
+		d := &Datasource{}
+		err := d.Configure(tt.config)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: Configure() error = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: Configure() error = %s", tt.name, err)
+			continue
+		}
+		if !slices.Equal(d.config.ArtifactFileTypes, tt.want) {
+			t.Errorf("%s: file types = %v, want %v", tt.name, d.config.ArtifactFileTypes, tt.want)
+		}
+	}
+}
+
+func TestDatasourceConfigure_VersionConstraint(t *testing.T) {
+	base := map[string]interface{}{
+		"artifactory_token":  "token",
+		"artifactory_server": "https://server.com/artifactory/api",
+		"artifact_name":      "win22",
+		"file_type":          "ova",
+	}
+
+	d := &Datasource{}
+	if err := d.Configure(base, map[string]interface{}{"version_constraint": ">= 2.3, < 3.0"}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if d.config.Select != SelectHighestSemver {
+		t.Errorf("select = %q, want %q when a version_constraint is set", d.config.Select, SelectHighestSemver)
+	}
+
+	d = &Datasource{}
+	if err := d.Configure(base, map[string]interface{}{"version_constraint": ">= 2.3", "select": "oldest"}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if d.config.Select != SelectOldest {
+		t.Errorf("select = %q, want an explicit select to be kept", d.config.Select)
+	}
+
+	d = &Datasource{}
+	err := d.Configure(base, map[string]interface{}{"version_constraint": "newer than 2"})
+	if err == nil || !strings.Contains(err.Error(), "version_constraint") {
+		t.Errorf("expected an invalid 'version_constraint' error, got %v", err)
+	}
+}
+
+func TestDatasourceExecute_Metadata(t *testing.T) {
+	server := fakeartifactory.New(t)
+	item := server.AddArtifact("/images/windows/win22/win22.ova", []byte("ova contents"), map[string]string{"release": "stable", "testing": "passed"})
+
+	for _, mode := range []map[string]interface{}{
+		{"artifact_name": "win22"},
+		{"aql": map[string]interface{}{"repositories": []string{"images"}, "name": "win22*"}},
+	} {
+		mode["artifactory_token"] = fakeartifactory.Token
+		mode["artifactory_server"] = server.ApiUrl()
+		mode["file_type"] = "ova"
+
+		d := &Datasource{}
+		if err := d.Configure(mode); err != nil {
+			t.Fatalf("Configure() error = %s", err)
+		}
+		value, err := d.Execute()
+		if err != nil {
+			t.Fatalf("Execute() error = %s", err)
+		}
+
+		want := map[string]string{
+			"sha256":        item.Sha256(),
+			"sha1":          item.Sha1(),
+			"md5":           item.Md5(),
+			"last_modified": item.Modified.Format(fakeartifactory.TimeFormat),
+			"created_by":    item.CreatedBy,
+			"modified_by":   item.ModifiedBy,
+			"repo":          "images",
+			"path":          "windows/win22",
+		}
+		for attr, wantValue := range want {
+			if got := value.GetAttr(attr).AsString(); got != wantValue {
+				t.Errorf("%v: %s = %q, want %q", mode, attr, got, wantValue)
+			}
+		}
+		if size, _ := value.GetAttr("size").AsBigFloat().Int64(); size != int64(len("ova contents")) {
+			t.Errorf("%v: size = %d", mode, size)
+		}
+		props := value.GetAttr("properties").AsValueMap()
+		if len(props) != 2 || props["release"].AsString() != "stable" || props["testing"].AsString() != "passed" {
+			t.Errorf("%v: properties = %#v", mode, props)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package artifactImage
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"packer-plugin-artifactory/internal/client"
+)
+
+// timeNow is replaced in tests so max_age can be checked against a fixed clock.
+var timeNow = time.Now
+
+// CreatedWindow bounds the creation date of the artifacts to consider; a zero time leaves that side open.
+type CreatedWindow struct {
+	After  time.Time
+	Before time.Time
+}
+
+// PrepareCreatedWindow validates created_after, created_before and max_age and combines them into one window.
+// max_age narrows created_after when it is the more recent of the two.
+func PrepareCreatedWindow(createdAfter, createdBefore, maxAge string) (CreatedWindow, []error) {
+	var window CreatedWindow
+	var errs []error
+
+	if createdAfter != "" {
+		after, err := time.Parse(time.RFC3339, createdAfter)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("'created_after' %q is not an RFC3339 timestamp (ex: 2024-01-31T00:00:00Z).", createdAfter))
+		}
+		window.After = after
+	}
+
+	if createdBefore != "" {
+		before, err := time.Parse(time.RFC3339, createdBefore)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("'created_before' %q is not an RFC3339 timestamp (ex: 2024-01-31T00:00:00Z).", createdBefore))
+		}
+		window.Before = before
+	}
+
+	if maxAge != "" {
+		age, err := time.ParseDuration(maxAge)
+		if err != nil || age <= 0 {
+			errs = append(errs, fmt.Errorf("'max_age' %q is not a positive duration (ex: 720h for 30 days).", maxAge))
+		} else if oldest := timeNow().Add(-age); oldest.After(window.After) {
+			window.After = oldest
+		}
+	}
+
+	if !window.After.IsZero() && !window.Before.IsZero() && !window.After.Before(window.Before) {
+		errs = append(errs, errors.New("'created_after' (or the date implied by 'max_age') must be earlier than 'created_before'."))
+	}
+	return window, errs
+}
+
+// IsSet reports whether either side of the window is bounded.
+func (w CreatedWindow) IsSet() bool {
+	return !w.After.IsZero() || !w.Before.IsZero()
+}
+
+func (w CreatedWindow) String() string {
+	after, before := "any time", "now"
+	if !w.After.IsZero() {
+		after = w.After.UTC().Format(time.RFC3339)
+	}
+	if !w.Before.IsZero() {
+		before = w.Before.UTC().Format(time.RFC3339)
+	}
+	return after + " and " + before
+}
+
+// Contains reports whether the artifact was created within the window. Artifacts without a readable creation
+// date are never within a bounded window.
+func (w CreatedWindow) Contains(artifact client.Artifact) bool {
+	if !w.IsSet() {
+		return true
+	}
+	created, err := artifact.CreatedTime()
+	if err != nil {
+		return false
+	}
+	if !w.After.IsZero() && !created.After(w.After) {
+		return false
+	}
+	if !w.Before.IsZero() && !created.Before(w.Before) {
+		return false
+	}
+	return true
+}
+
+// FilterByCreated keeps the artifacts created within the window.
+func FilterByCreated(window CreatedWindow, artifacts []client.Artifact) []client.Artifact {
+	var filtered []client.Artifact
+	for _, artifact := range artifacts {
+		if window.Contains(artifact) {
+			filtered = append(filtered, artifact)
+		}
+	}
+	return filtered
+}
+
+// StaleError reports that nothing was created within the window, along with the newest creation date that was found.
+func StaleError(window CreatedWindow, artifacts []client.Artifact) error {
+	SortNewestFirst(artifacts)
+	newest := "unknown"
+	if created, err := artifacts[0].CreatedTime(); err == nil {
+		newest = created.UTC().Format(time.RFC3339)
+	}
+	return fmt.Errorf("%d artifact(s) matched the search, but none was created between %s; the newest was created %s (%s)",
+		len(artifacts), window, newest, artifacts[0].RepoPath())
+}
+
+// CreationDate returns the creation date in RFC3339 (UTC) along with its unix timestamp. A date that can't be
+// parsed is returned as Artifactory reported it, with a zero timestamp.
+func CreationDate(artifact client.Artifact) (string, int64) {
+	created, err := artifact.CreatedTime()
+	if err != nil {
+		return artifact.Created, 0
+	}
+	return created.UTC().Format(time.RFC3339), created.Unix()
+}
@@ -0,0 +1,133 @@
+package artifactImage
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"packer-plugin-artifactory/internal/client"
+	"packer-plugin-artifactory/internal/testutil/fakeartifactory"
+)
+
+func fixedNow(t *testing.T, now time.Time) {
+	t.Helper()
+	timeNow = func() time.Time { return now }
+	t.Cleanup(func() { timeNow = time.Now })
+}
+
+func TestPrepareCreatedWindow(t *testing.T) {
+	fixedNow(t, time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC))
+
+	tests := []struct {
+		name                  string
+		after, before, age    string
+		wantAfter, wantBefore string
+		wantErr               string
+	}{
+		{name: "unset"},
+		{name: "after and before", after: "2024-01-01T00:00:00Z", before: "2024-02-01T00:00:00+01:00",
+			wantAfter: "2024-01-01T00:00:00Z", wantBefore: "2024-01-31T23:00:00Z"},
+		{name: "max age", age: "720h", wantAfter: "2024-05-31T00:00:00Z"},
+		{name: "max age is more recent than created_after", after: "2024-01-01T00:00:00Z", age: "24h", wantAfter: "2024-06-29T00:00:00Z"},
+		{name: "created_after is more recent than max age", after: "2024-06-29T12:00:00Z", age: "720h", wantAfter: "2024-06-29T12:00:00Z"},
+		{name: "bad timestamp", after: "2024-01-01", wantErr: "'created_after'"},
+		{name: "bad duration", age: "30d", wantErr: "'max_age'"},
+		{name: "negative duration", age: "-1h", wantErr: "'max_age'"},
+		{name: "empty window", after: "2024-02-01T00:00:00Z", before: "2024-01-01T00:00:00Z", wantErr: "must be earlier"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			window, errs := PrepareCreatedWindow(tt.after, tt.before, tt.age)
+			if tt.wantErr != "" {
+				if len(errs) != 1 || !strings.Contains(errs[0].Error(), tt.wantErr) {
+					t.Fatalf("PrepareCreatedWindow() errors = %v, want %q", errs, tt.wantErr)
+				}
+				return
+			}
+			if len(errs) > 0 {
+				t.Fatalf("unexpected errors %v", errs)
+			}
+			format := func(v time.Time) string {
+				if v.IsZero() {
+					return ""
+				}
+				return v.UTC().Format(time.RFC3339)
+			}
+			if format(window.After) != tt.wantAfter || format(window.Before) != tt.wantBefore {
+				t.Errorf("window = %s, want %s and %s", window, tt.wantAfter, tt.wantBefore)
+			}
+		})
+	}
+}
+
+func TestCreationDate(t *testing.T) {
+	created, unix := CreationDate(client.Artifact{Created: "2024-01-05T16:21:03.120+02:00"})
+	if created != "2024-01-05T14:21:03Z" || unix != 1704464463 {
+		t.Errorf("CreationDate() = %s, %d", created, unix)
+	}
+
+	created, unix = CreationDate(client.Artifact{Created: "last tuesday"})
+	if created != "last tuesday" || unix != 0 {
+		t.Errorf("CreationDate() = %s, %d for an unreadable date", created, unix)
+	}
+}
+
+func TestDatasourceExecute_Dates(t *testing.T) {
+	server := fakeartifactory.New(t)
+	server.AddArtifact("/images/win22/win22-jan.ova", []byte("jan"), nil)
+	server.AddArtifact("/images/win22/win22-feb.ova", []byte("feb"), nil)
+	jan, _ := server.Artifact("/images/win22/win22-jan.ova")
+	jan.Created = time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	feb, _ := server.Artifact("/images/win22/win22-feb.ova")
+	feb.Created = time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC)
+
+	fixedNow(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))
+
+	tests := []struct {
+		name     string
+		config   map[string]interface{}
+		wantName string
+		wantErr  string
+	}{
+		{name: "created_before", config: map[string]interface{}{"created_before": "2024-02-01T00:00:00Z"}, wantName: "win22-jan"},
+		{name: "created_after", config: map[string]interface{}{"created_after": "2024-01-01T00:00:00Z"}, wantName: "win22-feb"},
+		{name: "max_age", config: map[string]interface{}{"max_age": "720h"}, wantName: "win22-feb"},
+		{name: "max_age with aql", config: map[string]interface{}{"max_age": "720h", "aql": map[string]interface{}{"path": "win22"}}, wantName: "win22-feb"},
+		{name: "too old", config: map[string]interface{}{"max_age": "24h"},
+			wantErr: "none was created between 2024-02-29T00:00:00Z and now; the newest was created 2024-02-15T00:00:00Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config["artifactory_token"] = fakeartifactory.Token
+			tt.config["artifactory_server"] = server.ApiUrl()
+			tt.config["file_type"] = "ova"
+			if tt.config["aql"] == nil {
+				tt.config["artifact_name"] = "win22"
+			}
+
+			d := &Datasource{}
+			if err := d.Configure(tt.config); err != nil {
+				t.Fatalf("Configure() error = %s", err)
+			}
+			value, err := d.Execute()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Execute() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Execute() error = %s", err)
+			}
+			if name := value.GetAttr("name").AsString(); name != tt.wantName {
+				t.Errorf("name = %q, want %q", name, tt.wantName)
+			}
+			created, _ := time.Parse(time.RFC3339, value.GetAttr("creation_date").AsString())
+			if unix, _ := value.GetAttr("creation_timestamp").AsBigFloat().Int64(); unix != created.Unix() || unix == 0 {
+				t.Errorf("creation_timestamp = %d, creation_date = %s", unix, value.GetAttr("creation_date").AsString())
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package artifactImage
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"packer-plugin-artifactory/internal/client"
+)
+
+// Default property keys that mark an image as revoked or deprecated.
+const (
+	DefaultRevokedProperty    = "revoked"
+	DefaultDeprecatedProperty = "deprecated"
+)
+
+// Lifecycle is the revoked/deprecated state of an artifact. The property value is the reason, unless it is a
+// plain boolean (ex: revoked=true); a false value (ex: deprecated=false) is the same as not having the property.
+type Lifecycle struct {
+	Revoked           bool
+	RevokedReason     string
+	Deprecated        bool
+	DeprecationReason string
+}
+
+// lifecycleFlag reads one of the lifecycle properties.
+func lifecycleFlag(values []string) (bool, string) {
+	if len(values) == 0 {
+		return false, ""
+	}
+	value := strings.TrimSpace(strings.Join(values, ","))
+	if flag, err := strconv.ParseBool(value); err == nil {
+		return flag, ""
+	}
+	if strings.EqualFold(value, "no") {
+		return false, ""
+	}
+	if strings.EqualFold(value, "yes") {
+		return true, ""
+	}
+	return true, value
+}
+
+// ArtifactLifecycle reads the revoked and deprecated properties of the artifact.
+func ArtifactLifecycle(artifact client.Artifact, revokedProp, deprecatedProp string) Lifecycle {
+	var lifecycle Lifecycle
+	lifecycle.Revoked, lifecycle.RevokedReason = lifecycleFlag(artifact.Properties[revokedProp])
+	lifecycle.Deprecated, lifecycle.DeprecationReason = lifecycleFlag(artifact.Properties[deprecatedProp])
+	return lifecycle
+}
+
+// RevokedError refuses to resolve a revoked artifact.
+func RevokedError(artifact client.Artifact, revokedProp string, lifecycle Lifecycle) error {
+	reason := ""
+	if lifecycle.RevokedReason != "" {
+		reason = ": " + lifecycle.RevokedReason
+	}
+	return fmt.Errorf("The selected artifact %s has been revoked (property '%s')%s. "+
+		"Publish a replacement, or use a 'property_filter' with operator \"absent\" on '%s' to fall back to an earlier artifact.",
+		artifact.RepoPath(), revokedProp, reason, revokedProp)
+}
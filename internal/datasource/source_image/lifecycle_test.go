@@ -0,0 +1,119 @@
+package artifactImage
+
+import (
+	"strings"
+	"testing"
+
+	"packer-plugin-artifactory/internal/client"
+	"packer-plugin-artifactory/internal/testutil/fakeartifactory"
+)
+
+func TestArtifactLifecycle(t *testing.T) {
+	tests := []struct {
+		props map[string][]string
+		want  Lifecycle
+	}{
+		{nil, Lifecycle{}},
+		{map[string][]string{"revoked": {"true"}}, Lifecycle{Revoked: true}},
+		{map[string][]string{"revoked": {"false"}, "deprecated": {"no"}}, Lifecycle{}},
+		{map[string][]string{"revoked": {"CVE-2024-1234"}}, Lifecycle{Revoked: true, RevokedReason: "CVE-2024-1234"}},
+		{map[string][]string{"deprecated": {"yes"}}, Lifecycle{Deprecated: true}},
+		{map[string][]string{"deprecated": {"replaced by win22-v3"}}, Lifecycle{Deprecated: true, DeprecationReason: "replaced by win22-v3"}},
+		{map[string][]string{"lifecycle.revoked": {"1"}}, Lifecycle{}},
+	}
+
+	for _, tt := range tests {
+		got := ArtifactLifecycle(client.Artifact{Properties: tt.props}, DefaultRevokedProperty, DefaultDeprecatedProperty)
+		if got != tt.want {
+			t.Errorf("ArtifactLifecycle(%v) = %+v, want %+v", tt.props, got, tt.want)
+		}
+	}
+
+	got := ArtifactLifecycle(client.Artifact{Properties: map[string][]string{"lifecycle.revoked": {"1"}}}, "lifecycle.revoked", "lifecycle.deprecated")
+	if !got.Revoked {
+		t.Errorf("expected a custom revoked property to be read")
+	}
+}
+
+func TestDatasourceExecute_Lifecycle(t *testing.T) {
+	server := fakeartifactory.New(t)
+	server.AddArtifact("/images/win22/win22-v1.ova", []byte("v1"), map[string]string{"channel": "prod"})
+	server.AddArtifact("/images/win22/win22-v2.ova", []byte("v2"), map[string]string{"channel": "prod", "deprecated": "replaced by win22-v3"})
+	server.AddArtifact("/images/win22/win22-v3.ova", []byte("v3"), map[string]string{"channel": "prod", "status.revoked": "CVE-2024-1234"})
+	server.AddArtifact("/images/rhel9/rhel9.ova", []byte("rhel"), map[string]string{"revoked": "true"})
+
+	tests := []struct {
+		name           string
+		config         map[string]interface{}
+		wantName       string
+		wantDeprecated string
+		wantErr        string
+	}{
+		{
+			name:     "default keys ignore custom revoked property",
+			config:   map[string]interface{}{"artifact_name": "win22"},
+			wantName: "win22-v3",
+		},
+		{
+			name:    "revoked with custom key",
+			config:  map[string]interface{}{"artifact_name": "win22", "revoked_property": "status.revoked"},
+			wantErr: "/images/win22/win22-v3.ova has been revoked (property 'status.revoked'): CVE-2024-1234",
+		},
+		{
+			name:    "revoked with default key",
+			config:  map[string]interface{}{"artifact_name": "rhel9"},
+			wantErr: "/images/rhel9/rhel9.ova has been revoked",
+		},
+		{
+			name: "revoked with a raw query's own include",
+			config: map[string]interface{}{"aql": map[string]interface{}{
+				"query": `items.find({"repo":"images","name":{"$match":"rhel9*"}}).include("repo","path","name")`,
+			}},
+			wantErr: "/images/rhel9/rhel9.ova has been revoked",
+		},
+		{
+			name: "deprecated resolves",
+			config: map[string]interface{}{"artifact_name": "win22", "revoked_property": "status.revoked", "property_filter": []map[string]interface{}{
+				{"key": "status.revoked", "operator": "absent"},
+			}},
+			wantName:       "win22-v2",
+			wantDeprecated: "replaced by win22-v3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config["artifactory_token"] = fakeartifactory.Token
+			tt.config["artifactory_server"] = server.ApiUrl()
+			tt.config["file_type"] = "ova"
+
+			d := &Datasource{}
+			if err := d.Configure(tt.config); err != nil {
+				t.Fatalf("Configure() error = %s", err)
+			}
+			value, err := d.Execute()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Execute() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Execute() error = %s", err)
+			}
+
+			if name := value.GetAttr("name").AsString(); name != tt.wantName {
+				t.Errorf("name = %q, want %q", name, tt.wantName)
+			}
+			if value.GetAttr("revoked").True() {
+				t.Error("the selected artifact should never be revoked")
+			}
+			if deprecated := value.GetAttr("deprecated").True(); deprecated != (tt.wantDeprecated != "") {
+				t.Errorf("deprecated = %t", deprecated)
+			}
+			if reason := value.GetAttr("deprecation_reason").AsString(); reason != tt.wantDeprecated {
+				t.Errorf("deprecation_reason = %q, want %q", reason, tt.wantDeprecated)
+			}
+		})
+	}
+}
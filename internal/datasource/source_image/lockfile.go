@@ -0,0 +1,238 @@
+package artifactImage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"packer-plugin-artifactory/internal/client"
+)
+
+// LockfileVersion is the format written to the lockfile; files with a newer format are refused.
+const LockfileVersion = 1
+
+// LockUpdateEnv resolves every locked artifact again when set to a true value (ex: ARTIFACTORY_LOCK_UPDATE=1),
+// the same as setting 'lock_update' on each datasource.
+const LockUpdateEnv = "ARTIFACTORY_LOCK_UPDATE"
+
+// Lockfile pins the artifact each datasource resolved to, keyed by 'lock_key'.
+type Lockfile struct {
+	Version   int                  `json:"version"`
+	Artifacts map[string]LockEntry `json:"artifacts"`
+}
+
+// LockEntry is a single pinned artifact.
+type LockEntry struct {
+	RepoPath    string `json:"repo_path"`
+	ArtifactUri string `json:"artifact_uri"`
+	DownloadUri string `json:"download_uri"`
+	Sha256      string `json:"sha256,omitempty"`
+	// Only recorded, and only checked, when Artifactory has no sha256 for the artifact
+	Sha1 string `json:"sha1,omitempty"`
+	// Fingerprint of the search settings the artifact was resolved with
+	Search     string `json:"search"`
+	ResolvedAt string `json:"resolved_at"`
+}
+
+// lockfileMu serializes updates from datasources running in the same process. Packer runs each datasource in its
+// own plugin process, so UpdateLockfile also holds an OS lock on '<lockfile>.lock' while it reads, changes, and
+// writes the file; the OS lock alone doesn't exclude goroutines of one process from each other.
+var lockfileMu sync.Mutex
+
+// ReadLockfile loads the lockfile at path; a missing file is an empty lockfile.
+func ReadLockfile(path string) (*Lockfile, error) {
+	lockfile := &Lockfile{Version: LockfileVersion, Artifacts: map[string]LockEntry{}}
+
+	contents, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return lockfile, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read the lockfile %s: %s", path, err)
+	}
+	if err := json.Unmarshal(contents, lockfile); err != nil {
+		return nil, fmt.Errorf("The lockfile %s is not valid JSON: %s", path, err)
+	}
+	if lockfile.Version > LockfileVersion {
+		return nil, fmt.Errorf("The lockfile %s has version %d, but this plugin only understands version %d or lower; upgrade the plugin.", path, lockfile.Version, LockfileVersion)
+	}
+	if lockfile.Artifacts == nil {
+		lockfile.Artifacts = map[string]LockEntry{}
+	}
+	return lockfile, nil
+}
+
+// Write saves the lockfile to path through a temporary file in the same folder, then renames it into place.
+func (l *Lockfile) Write(path string) error {
+	l.Version = LockfileVersion
+	contents, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("Unable to write the lockfile %s: %s", path, err)
+	}
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(append(contents, '\n')); err != nil {
+		temp.Close()
+		return fmt.Errorf("Unable to write the lockfile %s: %s", path, err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("Unable to write the lockfile %s: %s", path, err)
+	}
+	if err := os.Chmod(temp.Name(), 0644); err != nil {
+		return fmt.Errorf("Unable to write the lockfile %s: %s", path, err)
+	}
+	if err := os.Rename(temp.Name(), path); err != nil {
+		return fmt.Errorf("Unable to write the lockfile %s: %s", path, err)
+	}
+	return nil
+}
+
+// UpdateLockfile records the entry under key, keeping every other entry in the file. Updates from other
+// datasources, in this process or another, wait their turn, so none of their entries are lost.
+func UpdateLockfile(path, key string, entry LockEntry) error {
+	lockfileMu.Lock()
+	defer lockfileMu.Unlock()
+
+	unlock, err := lockPath(path + ".lock")
+	if err != nil {
+		return fmt.Errorf("Unable to lock the lockfile %s: %s", path, err)
+	}
+	defer unlock()
+
+	lockfile, err := ReadLockfile(path)
+	if err != nil {
+		return err
+	}
+	lockfile.Artifacts[key] = entry
+	return lockfile.Write(path)
+}
+
+// lockPath takes an exclusive OS lock on the file at path, creating it if needed, and returns the function that
+// releases it. The file is left in place; removing it could let another process lock a file that no longer exists.
+func lockPath(path string) (func(), error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(file); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return func() {
+		unlockFile(file)
+		file.Close()
+	}, nil
+}
+
+// searchFingerprint hashes the settings that decide which artifact is resolved, so a pin isn't reused after
+// the search it came from has been changed in the template.
+func (d *Datasource) searchFingerprint() string {
+	settings, _ := json.Marshal(struct {
+		ArtifactName      string
+		NameMatch         string
+		ArtifactFileTypes []string
+		Repositories      []string
+		PathPrefix        string
+		ArtifactChannel   string
+		ArtifactFilter    map[string]string
+		PropertyFilters   []PropertyFilter
+		CreatedAfter      string
+		CreatedBefore     string
+		MaxAge            string
+		Select            string
+		VersionProperty   string
+		VersionConstraint string
+		Aql               *AqlConfig
+	}{
+		d.config.ArtifactName,
+		d.config.NameMatch,
+		d.config.ArtifactFileTypes,
+		d.config.Repositories,
+		d.config.PathPrefix,
+		d.config.ArtifactChannel,
+		d.config.ArtifactFilter,
+		d.config.PropertyFilters,
+		d.config.CreatedAfter,
+		d.config.CreatedBefore,
+		d.config.MaxAge,
+		d.config.Select,
+		d.config.VersionProperty,
+		d.config.VersionConstraint,
+		d.config.Aql,
+	})
+	sum := sha256.Sum256(settings)
+	return hex.EncodeToString(sum[:])
+}
+
+// lockedArtifact returns the artifact pinned for this datasource, after checking it still has the checksum it
+// was pinned with. Returns false when nothing is pinned yet.
+func (d *Datasource) lockedArtifact(artifClient *client.Client) (client.Artifact, bool, error) {
+	lockfile, err := ReadLockfile(d.config.Lockfile)
+	if err != nil {
+		return client.Artifact{}, false, err
+	}
+	entry, found := lockfile.Artifacts[d.config.LockKey]
+	if !found {
+		log.Printf("Nothing is pinned for '%s' in %s yet; searching for the artifact", d.config.LockKey, d.config.Lockfile)
+		return client.Artifact{}, false, nil
+	}
+
+	if entry.Search != d.searchFingerprint() {
+		return client.Artifact{}, false, fmt.Errorf("The artifact pinned for '%s' in %s was resolved with different search settings. "+
+			"Set 'lock_update = true' or %s=1 to resolve it again, or use a different 'lock_key'.", d.config.LockKey, d.config.Lockfile, LockUpdateEnv)
+	}
+
+	artifact, err := artifClient.GetArtifact(context.Background(), entry.RepoPath)
+	if err != nil {
+		return client.Artifact{}, false, fmt.Errorf("The artifact pinned for '%s' in %s could not be found: %s", d.config.LockKey, d.config.Lockfile, err)
+	}
+
+	algorithm, pinned, current := "sha256", entry.Sha256, artifact.Sha256
+	if pinned == "" {
+		algorithm, pinned, current = "sha1", entry.Sha1, artifact.Sha1
+	}
+	if pinned == "" {
+		return client.Artifact{}, false, fmt.Errorf("The entry for '%s' in %s has no checksum to verify %s with.", d.config.LockKey, d.config.Lockfile, entry.RepoPath)
+	}
+	if current != pinned {
+		return client.Artifact{}, false, fmt.Errorf("The checksum of %s has changed since it was pinned in %s: the %s was %s and is now %s. "+
+			"If the change is expected, set 'lock_update = true' or %s=1 to pin it again.", entry.RepoPath, d.config.Lockfile, algorithm, pinned, current, LockUpdateEnv)
+	}
+
+	log.Printf("Using %s, pinned for '%s' in %s", entry.RepoPath, d.config.LockKey, d.config.Lockfile)
+	return artifact, true, nil
+}
+
+// lockArtifact pins the selected artifact for this datasource.
+func (d *Datasource) lockArtifact(artifClient *client.Client, artifact client.Artifact) error {
+	entry := LockEntry{
+		RepoPath:    artifact.RepoPath(),
+		ArtifactUri: artifClient.StorageUrl(artifact.RepoPath()),
+		DownloadUri: artifClient.DownloadUrl(artifact.RepoPath()),
+		Sha256:      artifact.Sha256,
+		Search:      d.searchFingerprint(),
+		ResolvedAt:  timeNow().UTC().Format(time.RFC3339),
+	}
+	if entry.Sha256 == "" {
+		entry.Sha1 = artifact.Sha1
+	}
+	if err := UpdateLockfile(d.config.Lockfile, d.config.LockKey, entry); err != nil {
+		return err
+	}
+	log.Printf("Pinned %s for '%s' in %s", entry.RepoPath, d.config.LockKey, d.config.Lockfile)
+	return nil
+}
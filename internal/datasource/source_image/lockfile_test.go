@@ -0,0 +1,255 @@
+package artifactImage
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"packer-plugin-artifactory/internal/testutil/fakeartifactory"
+)
+
+func lockedDatasource(t *testing.T, server *fakeartifactory.Server, lockfile string, extra map[string]interface{}) *Datasource {
+	t.Helper()
+	raw := map[string]interface{}{
+		"artifactory_token":  fakeartifactory.Token,
+		"artifactory_server": server.ApiUrl(),
+		"artifact_name":      "win22",
+		"file_type":          "ova",
+		"lockfile":           lockfile,
+	}
+	for key, value := range extra {
+		raw[key] = value
+	}
+	d := &Datasource{}
+	if err := d.Configure(raw); err != nil {
+		t.Fatalf("Configure() error = %s", err)
+	}
+	return d
+}
+
+func TestDatasourceExecute_Lockfile(t *testing.T) {
+	t.Setenv(LockUpdateEnv, "")
+	server := fakeartifactory.New(t)
+	first := server.AddArtifact("/images/win22/win22-1.ova", []byte("first"), nil)
+	lockfile := filepath.Join(t.TempDir(), "artifactory.lock.json")
+
+	value, err := lockedDatasource(t, server, lockfile, nil).Execute()
+	if err != nil {
+		t.Fatalf("Execute() error = %s", err)
+	}
+	if got := value.GetAttr("name").AsString(); got != "win22-1" {
+		t.Fatalf("name = %q, want win22-1", got)
+	}
+
+	recorded, err := ReadLockfile(lockfile)
+	if err != nil {
+		t.Fatalf("ReadLockfile() error = %s", err)
+	}
+	entry := recorded.Artifacts["win22"]
+	if entry.RepoPath != first.RepoPath() || entry.Sha256 != first.Sha256() || entry.DownloadUri != server.DownloadUrl(first.RepoPath()) {
+		t.Fatalf("lockfile entry = %#v", entry)
+	}
+
+	// A newer artifact is ignored while the pin holds
+	server.AddArtifact("/images/win22/win22-2.ova", []byte("second"), nil)
+	value, err = lockedDatasource(t, server, lockfile, nil).Execute()
+	if err != nil {
+		t.Fatalf("Execute() error = %s", err)
+	}
+	if got := value.GetAttr("name").AsString(); got != "win22-1" {
+		t.Errorf("pinned name = %q, want win22-1", got)
+	}
+	if got := value.GetAttr("sha256").AsString(); got != first.Sha256() {
+		t.Errorf("pinned sha256 = %q", got)
+	}
+
+	// Until an update is asked for
+	t.Setenv(LockUpdateEnv, "1")
+	value, err = lockedDatasource(t, server, lockfile, nil).Execute()
+	if err != nil {
+		t.Fatalf("Execute() error = %s", err)
+	}
+	if got := value.GetAttr("name").AsString(); got != "win22-2" {
+		t.Errorf("updated name = %q, want win22-2", got)
+	}
+	recorded, _ = ReadLockfile(lockfile)
+	if got := recorded.Artifacts["win22"].RepoPath; got != "/images/win22/win22-2.ova" {
+		t.Errorf("updated lockfile entry = %q", got)
+	}
+}
+
+func TestDatasourceExecute_LockfileChecksumChanged(t *testing.T) {
+	t.Setenv(LockUpdateEnv, "")
+	server := fakeartifactory.New(t)
+	item := server.AddArtifact("/images/win22/win22.ova", []byte("original"), nil)
+	lockfile := filepath.Join(t.TempDir(), "artifactory.lock.json")
+
+	if _, err := lockedDatasource(t, server, lockfile, nil).Execute(); err != nil {
+		t.Fatalf("Execute() error = %s", err)
+	}
+
+	item.Content = []byte("replaced")
+	_, err := lockedDatasource(t, server, lockfile, nil).Execute()
+	if err == nil || !strings.Contains(err.Error(), "checksum of /images/win22/win22.ova has changed") {
+		t.Fatalf("Execute() error = %v, want a checksum error", err)
+	}
+
+	if _, err := lockedDatasource(t, server, lockfile, map[string]interface{}{"lock_update": true}).Execute(); err != nil {
+		t.Fatalf("Execute() with lock_update error = %s", err)
+	}
+	recorded, _ := ReadLockfile(lockfile)
+	if got := recorded.Artifacts["win22"].Sha256; got != item.Sha256() {
+		t.Errorf("sha256 after update = %q, want %q", got, item.Sha256())
+	}
+}
+
+func TestDatasourceExecute_LockfileEntries(t *testing.T) {
+	t.Setenv(LockUpdateEnv, "")
+	server := fakeartifactory.New(t)
+	server.AddArtifact("/images/win22/win22.ova", []byte("win22"), map[string]string{"release": "stable"})
+	server.AddArtifact("/images/rhel9/rhel9.ova", []byte("rhel9"), nil)
+	lockfile := filepath.Join(t.TempDir(), "artifactory.lock.json")
+
+	if _, err := lockedDatasource(t, server, lockfile, nil).Execute(); err != nil {
+		t.Fatalf("Execute() error = %s", err)
+	}
+	if _, err := lockedDatasource(t, server, lockfile, map[string]interface{}{"artifact_name": "rhel9"}).Execute(); err != nil {
+		t.Fatalf("Execute() error = %s", err)
+	}
+	recorded, _ := ReadLockfile(lockfile)
+	if len(recorded.Artifacts) != 2 {
+		t.Fatalf("lockfile entries = %#v, want win22 and rhel9", recorded.Artifacts)
+	}
+
+	// Changing the search invalidates the pin instead of silently reusing it
+	_, err := lockedDatasource(t, server, lockfile, map[string]interface{}{"filter": map[string]string{"release": "stable"}}).Execute()
+	if err == nil || !strings.Contains(err.Error(), "different search settings") {
+		t.Fatalf("Execute() error = %v, want a search settings error", err)
+	}
+
+	// A missing pinned artifact is an error, not a new search
+	recorded.Artifacts["rhel9"] = LockEntry{RepoPath: "/images/rhel9/gone.ova", Sha256: "abc", Search: recorded.Artifacts["rhel9"].Search}
+	if err := recorded.Write(lockfile); err != nil {
+		t.Fatalf("Write() error = %s", err)
+	}
+	_, err = lockedDatasource(t, server, lockfile, map[string]interface{}{"artifact_name": "rhel9"}).Execute()
+	if err == nil || !strings.Contains(err.Error(), "could not be found") {
+		t.Fatalf("Execute() error = %v, want a not found error", err)
+	}
+}
+
+// lockfileWriterEnv tells TestUpdateLockfileWriter which lockfile to update, and under which key prefix.
+const lockfileWriterEnv = "ARTIFACTORY_TEST_LOCKFILE_WRITER"
+
+const lockfileUpdates = 25
+
+// TestUpdateLockfileWriter only does something when run by TestUpdateLockfile_Concurrent, as a separate process
+// the way Packer runs each datasource.
+func TestUpdateLockfileWriter(t *testing.T) {
+	lockfile, prefix, ok := strings.Cut(os.Getenv(lockfileWriterEnv), "|")
+	if !ok {
+		return
+	}
+	for i := 0; i < lockfileUpdates; i++ {
+		if err := UpdateLockfile(lockfile, fmt.Sprintf("%s-%d", prefix, i), LockEntry{RepoPath: "/images/" + prefix}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestUpdateLockfile_Concurrent(t *testing.T) {
+	lockfile := filepath.Join(t.TempDir(), "artifactory.lock.json")
+	const processes, goroutines = 4, 2
+
+	var commands []*exec.Cmd
+	for p := 0; p < processes; p++ {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestUpdateLockfileWriter$")
+		cmd.Env = append(os.Environ(), fmt.Sprintf("%s=%s|process%d", lockfileWriterEnv, lockfile, p))
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+		commands = append(commands, cmd)
+	}
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < lockfileUpdates; i++ {
+				if err := UpdateLockfile(lockfile, fmt.Sprintf("goroutine%d-%d", g, i), LockEntry{}); err != nil {
+					t.Error(err)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	for _, cmd := range commands {
+		if err := cmd.Wait(); err != nil {
+			t.Fatalf("writer process failed: %s", err)
+		}
+	}
+
+	recorded, err := ReadLockfile(lockfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (processes + goroutines) * lockfileUpdates; len(recorded.Artifacts) != want {
+		t.Errorf("lockfile has %d entries, want %d; concurrent updates were lost", len(recorded.Artifacts), want)
+	}
+}
+
+func TestDatasourceConfigure_Lockfile(t *testing.T) {
+	t.Setenv(LockUpdateEnv, "")
+
+	tests := []struct {
+		name       string
+		config     map[string]interface{}
+		wantKey    string
+		wantUpdate bool
+		wantErr    string
+	}{
+		{name: "key defaults to artifact_name", config: map[string]interface{}{"artifact_name": "win22", "lockfile": "a.json"}, wantKey: "win22"},
+		{name: "explicit key", config: map[string]interface{}{"artifact_name": "win22", "lockfile": "a.json", "lock_key": "base"}, wantKey: "base"},
+		{name: "raw aql needs a key", config: map[string]interface{}{"aql": map[string]interface{}{"query": `items.find({"repo":"images"})`}, "lockfile": "a.json"},
+			wantErr: "'lock_key'"},
+		{name: "key without lockfile", config: map[string]interface{}{"artifact_name": "win22", "lock_key": "base"}, wantErr: "only apply with a 'lockfile'"},
+		{name: "update", config: map[string]interface{}{"artifact_name": "win22", "lockfile": "a.json", "lock_update": true}, wantKey: "win22", wantUpdate: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config["artifactory_token"] = fakeartifactory.Token
+			tt.config["artifactory_server"] = "https://artifactory.example.com/artifactory"
+			tt.config["file_type"] = "ova"
+
+			d := &Datasource{}
+			err := d.Configure(tt.config)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Configure() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Configure() error = %s", err)
+			}
+			if d.config.LockKey != tt.wantKey || d.config.LockUpdate != tt.wantUpdate {
+				t.Errorf("lock_key = %q, lock_update = %t", d.config.LockKey, d.config.LockUpdate)
+			}
+		})
+	}
+
+	t.Setenv(LockUpdateEnv, "true")
+	d := &Datasource{}
+	if err := d.Configure(map[string]interface{}{"artifactory_token": fakeartifactory.Token, "artifactory_server": "https://artifactory.example.com/artifactory",
+		"artifact_name": "win22", "file_type": "ova", "lockfile": "a.json"}); err != nil {
+		t.Fatalf("Configure() error = %s", err)
+	}
+	if !d.config.LockUpdate {
+		t.Errorf("%s=true did not turn on lock_update", LockUpdateEnv)
+	}
+}
@@ -0,0 +1,18 @@
+//go:build unix
+
+package artifactImage
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// lockFile blocks until this process holds an exclusive lock on the file.
+func lockFile(file *os.File) error {
+	return unix.FcntlFlock(file.Fd(), unix.F_SETLKW, &unix.Flock_t{Type: unix.F_WRLCK})
+}
+
+func unlockFile(file *os.File) error {
+	return unix.FcntlFlock(file.Fd(), unix.F_SETLK, &unix.Flock_t{Type: unix.F_UNLCK})
+}
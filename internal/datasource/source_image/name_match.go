@@ -0,0 +1,204 @@
+package artifactImage
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"packer-plugin-artifactory/internal/client"
+)
+
+// Ways to compare artifact_name with file names, set with 'name_match'.
+const (
+	NameContains = "contains"
+	NameExact    = "exact"
+	NamePrefix   = "prefix"
+	NameGlob     = "glob"
+	NameRegex    = "regex"
+)
+
+var nameMatchModes = []string{NameContains, NameExact, NamePrefix, NameGlob, NameRegex}
+
+// NameMatcher compares artifact_name with file names the way 'name_match' says. The zero value matches every name.
+type NameMatcher struct {
+	mode string
+	// artifact_name, in lower case for every mode but regex
+	pattern string
+	regex   *regexp.Regexp
+}
+
+// PrepareNameMatch validates 'name_match' against the artifact name it applies to, and returns the matcher for
+// them. A bad glob or regular expression is reported here, so 'packer validate' catches it.
+func PrepareNameMatch(mode, artifName string) (NameMatcher, []error) {
+	var errs []error
+
+	if !slices.Contains(nameMatchModes, mode) {
+		return NameMatcher{}, append(errs, fmt.Errorf("Unknown 'name_match' value %q; valid values are %s.", mode, strings.Join(nameMatchModes, ", ")))
+	}
+	if artifName == "" {
+		if mode != NameContains {
+			errs = append(errs, fmt.Errorf("'name_match' is set to '%s', but there is no 'artifact_name' to match.", mode))
+		}
+		return NameMatcher{}, errs
+	}
+
+	matcher := NameMatcher{mode: mode, pattern: strings.ToLower(artifName)}
+	switch mode {
+	case NameGlob:
+		if _, err := path.Match(matcher.pattern, ""); err != nil {
+			errs = append(errs, fmt.Errorf("'artifact_name' %q is not a valid glob: %s", artifName, err))
+		}
+	case NameRegex:
+		regex, err := regexp.Compile(artifName)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("'artifact_name' %q is not a valid regular expression: %s", artifName, err))
+		}
+		matcher.pattern, matcher.regex = artifName, regex
+	}
+	return matcher, errs
+}
+
+// Matches reports whether the artifact's name matches. 'contains' is the quick search's own rule: the whole file
+// name contains the name. The other modes compare the file name without its extension, so win22 matches
+// win22.ova, win22.ovf, and win22.vmtx alike; a glob has path.Match syntax ('*', '?', and '[...]' classes).
+// Every mode ignores case, whichever search found the artifact, except a regular expression, which is used as
+// written (it is case sensitive unless it starts with (?i), and not anchored unless it uses ^ and $).
+func (m NameMatcher) Matches(artifact client.Artifact) bool {
+	if m.pattern == "" {
+		return true
+	}
+	name := strings.ToLower(ArtifactName(artifact))
+
+	switch m.mode {
+	case NameExact:
+		return name == m.pattern
+	case NamePrefix:
+		return strings.HasPrefix(name, m.pattern)
+	case NameGlob:
+		matched, _ := path.Match(m.pattern, name)
+		return matched
+	case NameRegex:
+		return m.regex != nil && m.regex.MatchString(ArtifactName(artifact))
+	default:
+		return strings.Contains(strings.ToLower(artifact.Name), m.pattern)
+	}
+}
+
+// FilterByName keeps the artifacts whose name matches.
+func FilterByName(matcher NameMatcher, artifacts []client.Artifact) []client.Artifact {
+	var filtered []client.Artifact
+	for _, artifact := range artifacts {
+		if matcher.Matches(artifact) {
+			filtered = append(filtered, artifact)
+		}
+	}
+	return filtered
+}
+
+// quickSearchTerm returns what to send to the quick search, which only finds names containing a term. Results
+// are narrowed down with NameMatches afterwards. Returns false when there is nothing to search for: a regular
+// expression, or a glob made only of wildcards.
+func quickSearchTerm(mode, artifName string) (string, bool) {
+	switch mode {
+	case NameRegex:
+		return "", false
+	case NameGlob:
+		// The longest part without wildcards is in every name the glob matches
+		longest := ""
+		for _, part := range globLiterals(artifName) {
+			if len(part) > len(longest) {
+				longest = part
+			}
+		}
+		return longest, longest != ""
+	default:
+		return artifName, true
+	}
+}
+
+// globLiterals returns the runs of plain text between the wildcards and [...] classes of a glob, unescaped.
+func globLiterals(glob string) []string {
+	var parts []string
+	var part strings.Builder
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*', '?', '[':
+			if c == '[' {
+				for i++; i < len(glob) && glob[i] != ']'; i++ {
+					if glob[i] == '\\' {
+						i++
+					}
+				}
+			}
+			if part.Len() > 0 {
+				parts = append(parts, part.String())
+				part.Reset()
+			}
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				part.WriteByte(glob[i])
+			}
+		default:
+			part.WriteByte(c)
+		}
+	}
+	if part.Len() > 0 {
+		parts = append(parts, part.String())
+	}
+	return parts
+}
+
+// nameAqlCriteria returns the AQL criteria for the file name, or nil when AQL can't express the match. The name
+// is sent as written, along with its lower case form: depending on its database, Artifactory may compare names
+// case sensitively. NameMatcher then compares the results without regard to case, the same as for the name
+// search; a file named in some other mix of case (ex: Win22 for win22) is only found by the name search.
+func nameAqlCriteria(mode, artifName string) map[string]interface{} {
+	var pattern string
+	switch mode {
+	case NameExact:
+		pattern = artifName + ".*"
+	case NameGlob:
+		pattern = globAqlPattern(artifName) + ".*"
+	case NamePrefix:
+		pattern = artifName + "*"
+	case NameRegex:
+		return nil
+	default:
+		pattern = "*" + artifName + "*"
+	}
+
+	lower := strings.ToLower(pattern)
+	if lower == pattern {
+		return map[string]interface{}{"name": map[string]string{"$match": pattern}}
+	}
+	return map[string]interface{}{"$or": []map[string]interface{}{
+		{"name": map[string]string{"$match": pattern}},
+		{"name": map[string]string{"$match": lower}},
+	}}
+}
+
+// globAqlPattern turns a glob into an AQL $match pattern, which only knows '*' and '?': a [...] class becomes
+// a '?', and an escaped character is sent as is.
+func globAqlPattern(glob string) string {
+	var pattern strings.Builder
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case c == '[':
+			for i++; i < len(glob) && glob[i] != ']'; i++ {
+				if glob[i] == '\\' {
+					i++
+				}
+			}
+			pattern.WriteByte('?')
+		case c == '\\' && i+1 < len(glob):
+			i++
+			pattern.WriteByte(glob[i])
+		default:
+			pattern.WriteByte(c)
+		}
+	}
+	return pattern.String()
+}
@@ -0,0 +1,238 @@
+package artifactImage
+
+import (
+	"strings"
+	"testing"
+
+	"packer-plugin-artifactory/internal/client"
+	"packer-plugin-artifactory/internal/testutil/fakeartifactory"
+)
+
+func TestNameMatches(t *testing.T) {
+	tests := []struct {
+		mode    string
+		pattern string
+		matches []string
+		misses  []string
+	}{
+		{NameContains, "win22", []string{"win22.ova", "WIN22-core-debug.ovf", "my-win22.vmtx"}, []string{"win2022.ova"}},
+		{NameExact, "win22", []string{"win22.ova", "win22.ovf", "WIN22.vmtx"}, []string{"win22-core-debug.ova", "win22.1.ova"}},
+		{NamePrefix, "win22-core", []string{"win22-core.ova", "win22-core-debug.vmtx"}, []string{"win22.ova", "my-win22-core.ovf"}},
+		{NameGlob, "win22-*-202?", []string{"win22-core-2024.ova", "Win22-dc-2023.ovf"}, []string{"win22-core-2024-debug.vmtx", "win22-2024.ova"}},
+		{NameGlob, "win22-[cd]*-[0-9]", []string{"win22-core-1.ova", "WIN22-DC-2.ovf"}, []string{"win22-[cd]core-1.ova", "win22-std-1.ova", "win22-core-x.ova"}},
+		{NameGlob, `win22\*`, []string{"win22*.ova"}, []string{"win22-core.ova"}},
+		{NameRegex, `^win22-\d+\.\d+$`, []string{"win22-2.3.ova", "win22-10.0.vmtx"}, []string{"win22-2.3-debug.ova", "WIN22-2.3.ova"}},
+	}
+
+	for _, tt := range tests {
+		matcher, errs := PrepareNameMatch(tt.mode, tt.pattern)
+		if len(errs) > 0 {
+			t.Fatalf("PrepareNameMatch(%q, %q) = %v", tt.mode, tt.pattern, errs)
+		}
+		for _, name := range tt.matches {
+			if !matcher.Matches(client.Artifact{Name: name}) {
+				t.Errorf("%s %q should match %s", tt.mode, tt.pattern, name)
+			}
+		}
+		for _, name := range tt.misses {
+			if matcher.Matches(client.Artifact{Name: name}) {
+				t.Errorf("%s %q should not match %s", tt.mode, tt.pattern, name)
+			}
+		}
+	}
+}
+
+func TestPrepareNameMatch(t *testing.T) {
+	tests := []struct {
+		mode, name string
+		wantErr    string
+	}{
+		{NameContains, "", ""},
+		{NameGlob, "win22-*", ""},
+		{"fuzzy", "win22", "Unknown 'name_match'"},
+		{NameExact, "", "no 'artifact_name'"},
+		{NameRegex, "win22-(", "not a valid regular expression"},
+		{NameGlob, "win22-[core", "not a valid glob"},
+	}
+	for _, tt := range tests {
+		_, errs := PrepareNameMatch(tt.mode, tt.name)
+		if tt.wantErr == "" {
+			if len(errs) > 0 {
+				t.Errorf("PrepareNameMatch(%q, %q) = %v", tt.mode, tt.name, errs)
+			}
+			continue
+		}
+		if len(errs) != 1 || !strings.Contains(errs[0].Error(), tt.wantErr) {
+			t.Errorf("PrepareNameMatch(%q, %q) = %v, want %q", tt.mode, tt.name, errs, tt.wantErr)
+		}
+	}
+}
+
+func TestQuickSearchTerm(t *testing.T) {
+	tests := []struct {
+		mode, name string
+		want       string
+		wantOk     bool
+	}{
+		{NameExact, "win22", "win22", true},
+		{NameGlob, "w*-datacenter-?", "-datacenter-", true},
+		{NameGlob, "*?*", "", false},
+		{NameGlob, `win22-[cd]ore-\*-datacenter`, "ore-*-datacenter", true},
+		{NameGlob, "[abc]*", "", false},
+		{NameRegex, "^win22$", "", false},
+	}
+	for _, tt := range tests {
+		if got, ok := quickSearchTerm(tt.mode, tt.name); got != tt.want || ok != tt.wantOk {
+			t.Errorf("quickSearchTerm(%q, %q) = %q, %t", tt.mode, tt.name, got, ok)
+		}
+	}
+}
+
+func TestBuildAqlQuery_NameMatch(t *testing.T) {
+	tests := []struct {
+		search AqlSearch
+		want   string
+	}{
+		{AqlSearch{ArtifactName: "win22", NameMatch: NameExact, FileTypes: []string{".ova"}},
+			`items.find({"$and":[{"type":"file"},{"name":{"$match":"win22.*"}}]})`},
+		{AqlSearch{ArtifactName: "win22-", NameMatch: NamePrefix},
+			`items.find({"$and":[{"type":"file"},{"name":{"$match":"win22-*"}}]})`},
+		{AqlSearch{ArtifactName: `Win22-[cd]*-\?`, NameMatch: NameGlob},
+			`items.find({"$and":[{"type":"file"},{"$or":[{"name":{"$match":"Win22-?*-?.*"}},{"name":{"$match":"win22-?*-?.*"}}]}]})`},
+		{AqlSearch{ArtifactName: "^win22$", NameMatch: NameRegex, FileTypes: []string{".ova", ".ovf"}},
+			`items.find({"$and":[{"type":"file"},{"$or":[{"name":{"$match":"*.ova"}},{"name":{"$match":"*.ovf"}}]}]})`},
+	}
+	for _, tt := range tests {
+		got, err := BuildAqlQuery(&AqlConfig{}, tt.search)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("BuildAqlQuery(%+v) =\n%s\nwant\n%s", tt.search, got, tt.want)
+		}
+	}
+}
+
+func TestDatasourceExecute_NameMatch(t *testing.T) {
+	server := fakeartifactory.New(t)
+	server.AddArtifact("/images/win22/win22.ova", []byte("ova"), nil)
+	server.AddArtifact("/images/win22/win22.ovf", []byte("ovf"), nil)
+	server.AddArtifact("/images/win22/win22.vmtx", []byte("vmtx"), nil)
+	server.AddArtifact("/images/win22/win22-core-2.1.ova", []byte("core"), nil)
+	server.AddArtifact("/images/win22/win22-core-debug.ova", []byte("debug"), nil)
+
+	tests := []struct {
+		name     string
+		config   map[string]interface{}
+		wantFile string
+	}{
+		{name: "contains picks the latest", config: map[string]interface{}{"artifact_name": "win22", "file_type": "ova"}, wantFile: "win22-core-debug.ova"},
+		{name: "exact ova", config: map[string]interface{}{"artifact_name": "win22", "name_match": "exact", "file_type": "ova"}, wantFile: "win22.ova"},
+		{name: "exact ovf", config: map[string]interface{}{"artifact_name": "win22", "name_match": "exact", "file_type": "ovf"}, wantFile: "win22.ovf"},
+		{name: "exact vmtx", config: map[string]interface{}{"artifact_name": "WIN22", "name_match": "exact"}, wantFile: "win22.vmtx"},
+		{name: "prefix", config: map[string]interface{}{"artifact_name": "win22-core-2", "name_match": "prefix", "file_type": "ova"}, wantFile: "win22-core-2.1.ova"},
+		{name: "glob", config: map[string]interface{}{"artifact_name": "win22-core-?.?", "name_match": "glob", "file_type": "ova"}, wantFile: "win22-core-2.1.ova"},
+		{name: "regex", config: map[string]interface{}{"artifact_name": `^win22(-core-[\d.]+)?$`, "name_match": "regex", "file_type": "ova", "repositories": []string{"images"}}, wantFile: "win22-core-2.1.ova"},
+		{name: "exact with aql", config: map[string]interface{}{"artifact_name": "win22", "name_match": "exact", "file_type": "ova",
+			"aql": map[string]interface{}{"repositories": []string{"images"}}}, wantFile: "win22.ova"},
+		{name: "exact with aql ignores case", config: map[string]interface{}{"artifact_name": "WIN22", "name_match": "exact", "file_type": "ova",
+			"aql": map[string]interface{}{"repositories": []string{"images"}}}, wantFile: "win22.ova"},
+		{name: "glob class with aql", config: map[string]interface{}{"artifact_name": "Win22-[c]ore-[0-9].*", "name_match": "glob", "file_type": "ova",
+			"aql": map[string]interface{}{"repositories": []string{"images"}}}, wantFile: "win22-core-2.1.ova"},
+		{name: "regex with aql", config: map[string]interface{}{"artifact_name": `^win22$`, "name_match": "regex", "file_types": []string{"ovf", "ova"},
+			"aql": map[string]interface{}{"repositories": []string{"images"}}}, wantFile: "win22.ovf"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config["artifactory_token"] = fakeartifactory.Token
+			tt.config["artifactory_server"] = server.ApiUrl()
+
+			d := &Datasource{}
+			if err := d.Configure(tt.config); err != nil {
+				t.Fatalf("Configure() error = %s", err)
+			}
+			value, err := d.Execute()
+			if err != nil {
+				t.Fatalf("Execute() error = %s", err)
+			}
+			if uri := value.GetAttr("download_uri").AsString(); !strings.HasSuffix(uri, "/"+tt.wantFile) {
+				t.Errorf("download_uri = %q, want %s", uri, tt.wantFile)
+			}
+		})
+	}
+}
+
+func TestDatasourceExecute_NameMatchQuery(t *testing.T) {
+	server := fakeartifactory.New(t)
+	server.AddArtifact("/images/win22/win22.ova", []byte("win22"), nil)
+	server.AddArtifact("/images/ubuntu/ubuntu.ova", []byte("ubuntu"), nil)
+
+	tests := []struct {
+		name      string
+		config    map[string]interface{}
+		wantQuery []string
+	}{
+		{name: "name as written and in lower case", config: map[string]interface{}{"artifact_name": "WIN22", "name_match": "exact",
+			"aql": map[string]interface{}{"repositories": []string{"images"}}},
+			wantQuery: []string{`{"$or":[{"name":{"$match":"WIN22.*"}},{"name":{"$match":"win22.*"}}]}`}},
+		{name: "contains", config: map[string]interface{}{"artifact_name": "win22", "aql": map[string]interface{}{"path": "win22"}},
+			wantQuery: []string{`{"name":{"$match":"*win22*"}}`}},
+		{name: "regex searches the repositories", config: map[string]interface{}{"artifact_name": `^win\d+$`, "name_match": "regex", "repositories": []string{"images"}},
+			wantQuery: []string{`{"repo":"images"}`, `{"name":{"$match":"*.ova"}}`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config["artifactory_token"] = fakeartifactory.Token
+			tt.config["artifactory_server"] = server.ApiUrl()
+			tt.config["file_type"] = "ova"
+
+			d := &Datasource{}
+			if err := d.Configure(tt.config); err != nil {
+				t.Fatalf("Configure() error = %s", err)
+			}
+			if _, err := d.Execute(); err != nil {
+				t.Fatalf("Execute() error = %s", err)
+			}
+			queries := server.AqlQueries()
+			if len(queries) == 0 {
+				t.Fatal("no AQL query was sent")
+			}
+			query := queries[len(queries)-1]
+			for _, want := range tt.wantQuery {
+				if !strings.Contains(query, want) {
+					t.Errorf("query %s\nwant it to include %s", query, want)
+				}
+			}
+			if strings.Contains(query, "???") {
+				t.Errorf("query %s should not replace the name with wildcards", query)
+			}
+		})
+	}
+}
+
+func TestDatasourceConfigure_NameMatchNeedsScope(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		wantErr bool
+	}{
+		{name: "regex alone", config: map[string]interface{}{"artifact_name": "^win22$", "name_match": "regex"}, wantErr: true},
+		{name: "glob of wildcards alone", config: map[string]interface{}{"artifact_name": "*?*", "name_match": "glob"}, wantErr: true},
+		{name: "regex with repositories", config: map[string]interface{}{"artifact_name": "^win22$", "name_match": "regex", "repositories": []string{"images"}}},
+		{name: "regex with aql", config: map[string]interface{}{"artifact_name": "^win22$", "name_match": "regex", "aql": map[string]interface{}{"path": "windows"}}},
+	}
+	for _, tt := range tests {
+		tt.config["artifactory_token"] = "token"
+		tt.config["artifactory_server"] = "https://server.com/artifactory/api"
+
+		err := (&Datasource{}).Configure(tt.config)
+		if tt.wantErr != (err != nil && strings.Contains(err.Error(), "set 'repositories' or use an 'aql' block")) {
+			t.Errorf("%s: Configure() error = %v", tt.name, err)
+		}
+		if !tt.wantErr && err != nil {
+			t.Errorf("%s: Configure() error = %s", tt.name, err)
+		}
+	}
+}
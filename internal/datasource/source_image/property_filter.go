@@ -0,0 +1,178 @@
+package artifactImage
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"packer-plugin-artifactory/internal/client"
+)
+
+// Property filter operators, set with 'operator'.
+const (
+	PropertyEquals    = "eq"
+	PropertyNotEquals = "ne"
+	PropertyExists    = "exists"
+	PropertyAbsent    = "absent"
+	PropertyMatch     = "match"
+	PropertyRegex     = "regex"
+)
+
+var propertyOperators = []string{PropertyEquals, PropertyNotEquals, PropertyExists, PropertyAbsent, PropertyMatch, PropertyRegex}
+
+// --> If making changes to this section, make sure the hcl2spec gets updated as well!
+// PropertyFilter is a single condition on an artifact property. An artifact must pass every filter.
+type PropertyFilter struct {
+	Key string `mapstructure:"key" required:"true"`
+	// eq (default), ne, exists, absent, match (with '*' and '?' wildcards), or regex
+	Operator string `mapstructure:"operator" required:"false"`
+	// Values to compare with; eq, match and regex pass if any value matches, ne passes if none do
+	Values []string `mapstructure:"values" required:"false"`
+}
+
+// Prepare defaults the operator and validates the filter; index is its position in the template, for the error messages.
+func (f *PropertyFilter) Prepare(index int) []error {
+	var errs []error
+
+	if f.Operator == "" {
+		f.Operator = PropertyEquals
+	}
+	if f.Key == "" {
+		errs = append(errs, fmt.Errorf("'property_filter' %d is missing the property 'key'.", index))
+	}
+	if !slices.Contains(propertyOperators, f.Operator) {
+		errs = append(errs, fmt.Errorf("'property_filter' %d has an unknown 'operator' %q; valid operators are %s.", index, f.Operator, strings.Join(propertyOperators, ", ")))
+		return errs
+	}
+
+	switch f.Operator {
+	case PropertyExists, PropertyAbsent:
+		if len(f.Values) > 0 {
+			errs = append(errs, fmt.Errorf("'property_filter' %d uses the '%s' operator, which does not take 'values'.", index, f.Operator))
+		}
+	default:
+		if len(f.Values) == 0 {
+			errs = append(errs, fmt.Errorf("'property_filter' %d uses the '%s' operator, which needs at least one entry in 'values'.", index, f.Operator))
+		}
+	}
+
+	if f.Operator == PropertyRegex {
+		for _, value := range f.Values {
+			if _, err := regexp.Compile(value); err != nil {
+				errs = append(errs, fmt.Errorf("'property_filter' %d has an invalid regular expression %q: %s", index, value, err))
+			}
+		}
+	}
+	return errs
+}
+
+// wildcardRegexp turns a pattern using '*' and '?' into an anchored regular expression.
+func wildcardRegexp(pattern string) *regexp.Regexp {
+	quoted := regexp.QuoteMeta(pattern)
+	quoted = strings.ReplaceAll(quoted, `\*`, ".*")
+	quoted = strings.ReplaceAll(quoted, `\?`, ".")
+	return regexp.MustCompile("^" + quoted + "$")
+}
+
+// Matches reports whether the artifact passes the filter. Values are compared case sensitively, like Artifactory does.
+func (f PropertyFilter) Matches(artifact client.Artifact) bool {
+	have, exists := artifact.Properties[f.Key]
+
+	switch f.Operator {
+	case PropertyExists:
+		return exists
+	case PropertyAbsent:
+		return !exists
+	case PropertyNotEquals:
+		for _, value := range have {
+			if slices.Contains(f.Values, value) {
+				return false
+			}
+		}
+		return true
+	}
+
+	for _, want := range f.Values {
+		for _, value := range have {
+			switch f.Operator {
+			case PropertyMatch:
+				if wildcardRegexp(want).MatchString(value) {
+					return true
+				}
+			case PropertyRegex:
+				if regexp.MustCompile(want).MatchString(value) {
+					return true
+				}
+			default:
+				if value == want {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// String describes the filter for error messages, ex: revoked ne [true].
+func (f PropertyFilter) String() string {
+	if len(f.Values) == 0 {
+		return f.Key + " " + f.Operator
+	}
+	return f.Key + " " + f.Operator + " [" + strings.Join(f.Values, " ") + "]"
+}
+
+// PropertyFilterStrings describes each of the filters.
+func PropertyFilterStrings(filters []PropertyFilter) []string {
+	var descriptions []string
+	for _, filter := range filters {
+		descriptions = append(descriptions, filter.String())
+	}
+	return descriptions
+}
+
+// FilterByPropertyFilters keeps the artifacts that pass every filter.
+func FilterByPropertyFilters(filters []PropertyFilter, artifacts []client.Artifact) []client.Artifact {
+	var filtered []client.Artifact
+	for _, artifact := range artifacts {
+		passes := true
+		for _, filter := range filters {
+			if !filter.Matches(artifact) {
+				passes = false
+				break
+			}
+		}
+		if passes {
+			filtered = append(filtered, artifact)
+		}
+	}
+	return filtered
+}
+
+// aqlCriteria returns the AQL clause for the filter, or nil when it can only be checked once the results are back.
+// AQL's $ne and $nmatch leave out artifacts without the property, and AQL has no regular expressions, so ne,
+// absent and regex are not sent to the server.
+func (f PropertyFilter) aqlCriteria() map[string]interface{} {
+	field := "@" + f.Key
+
+	var clauses []map[string]interface{}
+	switch f.Operator {
+	case PropertyExists:
+		return map[string]interface{}{field: map[string]string{"$match": "*"}}
+	case PropertyEquals:
+		for _, value := range f.Values {
+			clauses = append(clauses, map[string]interface{}{field: value})
+		}
+	case PropertyMatch:
+		for _, value := range f.Values {
+			clauses = append(clauses, map[string]interface{}{field: map[string]string{"$match": value}})
+		}
+	default:
+		return nil
+	}
+
+	if len(clauses) == 1 {
+		return clauses[0]
+	}
+	return map[string]interface{}{"$or": clauses}
+}
@@ -0,0 +1,98 @@
+package artifactImage
+
+import (
+	"strings"
+	"testing"
+
+	"packer-plugin-artifactory/internal/client"
+)
+
+func TestPropertyFilterMatches(t *testing.T) {
+	artifact := client.Artifact{Repo: "images", Name: "win22.ova", Properties: map[string][]string{
+		"release": {"stable"},
+		"testing": {"passed", "signed"},
+		"build":   {"win22-2024.03.1"},
+	}}
+
+	tests := []struct {
+		filter PropertyFilter
+		want   bool
+	}{
+		{PropertyFilter{Key: "release", Operator: "eq", Values: []string{"stable"}}, true},
+		{PropertyFilter{Key: "release", Operator: "eq", Values: []string{"lts", "stable"}}, true},
+		{PropertyFilter{Key: "release", Operator: "eq", Values: []string{"Stable"}}, false},
+		{PropertyFilter{Key: "testing", Operator: "ne", Values: []string{"failed"}}, true},
+		{PropertyFilter{Key: "testing", Operator: "ne", Values: []string{"failed", "signed"}}, false},
+		{PropertyFilter{Key: "revoked", Operator: "ne", Values: []string{"true"}}, true},
+		{PropertyFilter{Key: "release", Operator: "exists"}, true},
+		{PropertyFilter{Key: "revoked", Operator: "exists"}, false},
+		{PropertyFilter{Key: "revoked", Operator: "absent"}, true},
+		{PropertyFilter{Key: "release", Operator: "absent"}, false},
+		{PropertyFilter{Key: "build", Operator: "match", Values: []string{"win22-2024.*"}}, true},
+		{PropertyFilter{Key: "build", Operator: "match", Values: []string{"win22-2023.??.?"}}, false},
+		{PropertyFilter{Key: "build", Operator: "regex", Values: []string{`^win22-\d{4}\.03\.\d+$`}}, true},
+		{PropertyFilter{Key: "build", Operator: "regex", Values: []string{`^rhel`}}, false},
+		{PropertyFilter{Key: "revoked", Operator: "regex", Values: []string{`.*`}}, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.filter.Matches(artifact); got != tt.want {
+			t.Errorf("%s: Matches() = %t, want %t", tt.filter, got, tt.want)
+		}
+	}
+}
+
+func TestPropertyFilterPrepare(t *testing.T) {
+	tests := []struct {
+		filter  PropertyFilter
+		wantErr string
+	}{
+		{filter: PropertyFilter{Key: "release", Values: []string{"stable"}}},
+		{filter: PropertyFilter{Key: "revoked", Operator: "absent"}},
+		{filter: PropertyFilter{Values: []string{"stable"}}, wantErr: "missing the property 'key'"},
+		{filter: PropertyFilter{Key: "release", Operator: "like", Values: []string{"stable"}}, wantErr: "unknown 'operator'"},
+		{filter: PropertyFilter{Key: "release", Operator: "ne"}, wantErr: "needs at least one entry in 'values'"},
+		{filter: PropertyFilter{Key: "release", Operator: "exists", Values: []string{"x"}}, wantErr: "does not take 'values'"},
+		{filter: PropertyFilter{Key: "release", Operator: "regex", Values: []string{"("}}, wantErr: "invalid regular expression"},
+	}
+
+	for _, tt := range tests {
+		errs := tt.filter.Prepare(1)
+		if tt.wantErr == "" {
+			if len(errs) > 0 {
+				t.Errorf("%s: unexpected errors %v", tt.filter, errs)
+			}
+			continue
+		}
+		if len(errs) != 1 || !strings.Contains(errs[0].Error(), tt.wantErr) {
+			t.Errorf("%s: Prepare() = %v, want %q", tt.filter, errs, tt.wantErr)
+		}
+	}
+
+	filter := PropertyFilter{Key: "release", Values: []string{"stable"}}
+	filter.Prepare(1)
+	if filter.Operator != PropertyEquals {
+		t.Errorf("operator = %q, want the default %q", filter.Operator, PropertyEquals)
+	}
+}
+
+func TestBuildAqlQuery_PropertyFilters(t *testing.T) {
+	filters := []PropertyFilter{
+		{Key: "release", Operator: "eq", Values: []string{"stable", "lts"}},
+		{Key: "build", Operator: "match", Values: []string{"2024.*"}},
+		{Key: "signed", Operator: "exists"},
+		{Key: "revoked", Operator: "ne", Values: []string{"true"}},
+		{Key: "testing", Operator: "absent"},
+		{Key: "owner", Operator: "regex", Values: []string{"^team-"}},
+	}
+	want := `items.find({"$and":[{"type":"file"},{"repo":"images"},{"$or":[{"@release":"stable"},{"@release":"lts"}]},` +
+		`{"@build":{"$match":"2024.*"}},{"@signed":{"$match":"*"}}]})`
+
+	got, err := BuildAqlQuery(&AqlConfig{Repositories: []string{"images"}}, AqlSearch{Filters: filters})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("BuildAqlQuery() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,107 @@
+package artifactImage
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"packer-plugin-artifactory/internal/client"
+)
+
+// Scope limits the search to some repositories, and to a folder within them; the zero value searches everywhere.
+type Scope struct {
+	Repositories []string
+	// Folder path within the repository, without leading or trailing slashes
+	PathPrefix string
+}
+
+// PrepareScope validates 'repositories' and 'path_prefix', returning the scope they describe.
+func PrepareScope(repos []string, pathPrefix string) (Scope, []error) {
+	var errs []error
+
+	scope := Scope{PathPrefix: strings.Trim(pathPrefix, "/")}
+	for i, repo := range repos {
+		repo = strings.TrimSpace(repo)
+		if repo == "" || strings.Contains(repo, "/") {
+			errs = append(errs, fmt.Errorf("'repositories' entry %d (%q) is not a repository key.", i+1, repos[i]))
+			continue
+		}
+		scope.Repositories = append(scope.Repositories, repo)
+	}
+	if strings.ContainsAny(scope.PathPrefix, "*?") {
+		errs = append(errs, fmt.Errorf("'path_prefix' %q cannot contain wildcards; use the 'aql' block's 'path' for a wildcard match.", pathPrefix))
+	}
+	return scope, errs
+}
+
+// IsSet reports whether the scope limits the search at all.
+func (s Scope) IsSet() bool {
+	return len(s.Repositories) > 0 || s.PathPrefix != ""
+}
+
+func (s Scope) String() string {
+	repos := "every repository"
+	if len(s.Repositories) > 0 {
+		repos = "repositories " + strings.Join(s.Repositories, ", ")
+	}
+	if s.PathPrefix == "" {
+		return repos
+	}
+	return repos + " under " + s.PathPrefix + "/"
+}
+
+// Contains reports whether a file in the repo and folder is within the scope. The prefix is matched on whole
+// folder names, so windows/prod covers windows/prod/win22 but not windows/prod-old.
+func (s Scope) Contains(repo, folder string) bool {
+	if len(s.Repositories) > 0 && !slices.Contains(s.Repositories, repo) {
+		return false
+	}
+	if s.PathPrefix == "" {
+		return true
+	}
+	return folder == s.PathPrefix || strings.HasPrefix(folder, s.PathPrefix+"/")
+}
+
+// ContainsRepoPath is Contains for a /repo/folder/file path.
+func (s Scope) ContainsRepoPath(repoPath string) bool {
+	repo, rest, _ := strings.Cut(strings.TrimPrefix(repoPath, "/"), "/")
+	folder := ""
+	if i := strings.LastIndex(rest, "/"); i >= 0 {
+		folder = rest[:i]
+	}
+	return s.Contains(repo, folder)
+}
+
+// FilterByScope keeps the artifacts within the scope.
+func FilterByScope(scope Scope, artifacts []client.Artifact) []client.Artifact {
+	var filtered []client.Artifact
+	for _, artifact := range artifacts {
+		if scope.Contains(artifact.Repo, artifact.Path) {
+			filtered = append(filtered, artifact)
+		}
+	}
+	return filtered
+}
+
+// aqlCriteria returns the AQL clauses for the scope, to be combined with AND.
+func (s Scope) aqlCriteria() []map[string]interface{} {
+	var clauses []map[string]interface{}
+
+	if len(s.Repositories) == 1 {
+		clauses = append(clauses, map[string]interface{}{"repo": s.Repositories[0]})
+	} else if len(s.Repositories) > 1 {
+		var repos []map[string]interface{}
+		for _, repo := range s.Repositories {
+			repos = append(repos, map[string]interface{}{"repo": repo})
+		}
+		clauses = append(clauses, map[string]interface{}{"$or": repos})
+	}
+
+	if s.PathPrefix != "" {
+		clauses = append(clauses, map[string]interface{}{"$or": []map[string]interface{}{
+			{"path": s.PathPrefix},
+			{"path": map[string]string{"$match": s.PathPrefix + "/*"}},
+		}})
+	}
+	return clauses
+}
@@ -0,0 +1,133 @@
+package artifactImage
+
+import (
+	"strings"
+	"testing"
+
+	"packer-plugin-artifactory/internal/testutil/fakeartifactory"
+)
+
+func TestPrepareScope(t *testing.T) {
+	scope, errs := PrepareScope([]string{" images ", "vm-prod"}, "/windows/prod/")
+	if len(errs) > 0 {
+		t.Fatalf("PrepareScope() errors = %v", errs)
+	}
+	if strings.Join(scope.Repositories, ",") != "images,vm-prod" || scope.PathPrefix != "windows/prod" {
+		t.Errorf("PrepareScope() = %+v", scope)
+	}
+
+	_, errs = PrepareScope([]string{"images", "images/windows", ""}, "windows/*")
+	if len(errs) != 3 {
+		t.Errorf("PrepareScope() errors = %v, want 2 bad repositories and a wildcard path", errs)
+	}
+}
+
+func TestScopeContains(t *testing.T) {
+	scope := Scope{Repositories: []string{"images"}, PathPrefix: "windows/prod"}
+
+	tests := []struct {
+		repoPath string
+		want     bool
+	}{
+		{"/images/windows/prod/win22.ova", true},
+		{"/images/windows/prod/2024/win22.ova", true},
+		{"/images/windows/prod-old/win22.ova", false},
+		{"/images/windows/win22.ova", false},
+		{"/images/win22.ova", false},
+		{"/scratch/windows/prod/win22.ova", false},
+	}
+	for _, tt := range tests {
+		if got := scope.ContainsRepoPath(tt.repoPath); got != tt.want {
+			t.Errorf("ContainsRepoPath(%s) = %t, want %t", tt.repoPath, got, tt.want)
+		}
+	}
+
+	if !(Scope{}).ContainsRepoPath("/anything/at/all.ova") {
+		t.Error("an empty scope should contain every artifact")
+	}
+}
+
+func TestBuildAqlQuery_Scope(t *testing.T) {
+	scope := Scope{Repositories: []string{"images", "vm-prod"}, PathPrefix: "windows"}
+	want := `items.find({"$and":[{"type":"file"},{"$or":[{"repo":"images"},{"repo":"vm-prod"}]},` +
+		`{"$or":[{"path":"windows"},{"path":{"$match":"windows/*"}}]},{"name":{"$match":"*win22*"}}]})`
+
+	got, err := BuildAqlQuery(&AqlConfig{}, AqlSearch{ArtifactName: "win22", Scope: scope})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("BuildAqlQuery() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestDatasourceExecute_Scope(t *testing.T) {
+	server := fakeartifactory.New(t)
+	server.AddArtifact("/images/windows/prod/win22.ova", []byte("prod"), nil)
+	server.AddArtifact("/images/windows/prod-old/win22.ova", []byte("old"), nil)
+	server.AddArtifact("/scratch/windows/prod/win22-test.ova", []byte("test"), nil)
+
+	tests := []struct {
+		name     string
+		config   map[string]interface{}
+		wantPath string
+		wantErr  string
+	}{
+		{
+			name:     "no scope",
+			config:   map[string]interface{}{"artifact_name": "win22"},
+			wantPath: "/scratch/windows/prod/win22-test.ova",
+		},
+		{
+			name:     "repositories",
+			config:   map[string]interface{}{"artifact_name": "win22", "repositories": []string{"images"}},
+			wantPath: "/images/windows/prod-old/win22.ova",
+		},
+		{
+			name:     "repositories and path prefix",
+			config:   map[string]interface{}{"artifact_name": "win22", "repositories": []string{"images"}, "path_prefix": "windows/prod"},
+			wantPath: "/images/windows/prod/win22.ova",
+		},
+		{
+			name:     "structured aql",
+			config:   map[string]interface{}{"repositories": []string{"images"}, "path_prefix": "windows/prod", "aql": map[string]interface{}{"name": "win22*"}},
+			wantPath: "/images/windows/prod/win22.ova",
+		},
+		{
+			name:     "raw aql",
+			config:   map[string]interface{}{"repositories": []string{"images"}, "path_prefix": "/windows/prod/", "aql": map[string]interface{}{"query": `items.find({"name":{"$match":"win22*"}})`}},
+			wantPath: "/images/windows/prod/win22.ova",
+		},
+		{
+			name:    "nothing in scope",
+			config:  map[string]interface{}{"artifact_name": "win22", "repositories": []string{"vm-prod"}},
+			wantErr: "only repositories vm-prod were searched",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config["artifactory_token"] = fakeartifactory.Token
+			tt.config["artifactory_server"] = server.ApiUrl()
+			tt.config["file_type"] = "ova"
+
+			d := &Datasource{}
+			if err := d.Configure(tt.config); err != nil {
+				t.Fatalf("Configure() error = %s", err)
+			}
+			value, err := d.Execute()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Execute() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Execute() error = %s", err)
+			}
+			if uri := value.GetAttr("download_uri").AsString(); uri != server.DownloadUrl(tt.wantPath) {
+				t.Errorf("download_uri = %q, want %s", uri, tt.wantPath)
+			}
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package artifactImage
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"packer-plugin-artifactory/internal/client"
+
+	"github.com/hashicorp/go-version"
+)
+
+// normalizeExt returns the file extension with a leading '.', defaulting to '.vmtx' like the name search always has.
+func normalizeExt(ext string) string {
+	if ext == "" {
+		return ".vmtx"
+	}
+	if !strings.HasPrefix(ext, ".") {
+		return "." + ext
+	}
+	return ext
+}
+
+// DisplayFileTypes lists the extensions without their leading '.', for messages.
+func DisplayFileTypes(exts []string) string {
+	var types []string
+	for _, ext := range exts {
+		types = append(types, strings.TrimPrefix(ext, "."))
+	}
+	return strings.Join(types, ", ")
+}
+
+// FileType is the artifact's extension without the leading '.', ex: ova.
+func FileType(artifact client.Artifact) string {
+	return strings.TrimPrefix(path.Ext(artifact.Name), ".")
+}
+
+// searchByName runs the quick search and returns the details of every match with one of the file types.
+func (d *Datasource) searchByName(artifClient *client.Client, exts []string) ([]client.Artifact, error) {
+	ctx := context.Background()
+	scope := d.scope()
+	term, _ := quickSearchTerm(d.config.NameMatch, d.config.ArtifactName)
+	repoPaths, err := artifClient.SearchByName(ctx, term, scope.Repositories)
+	if err != nil {
+		return nil, err
+	}
+
+	// Skip the detail lookups for anything that can't be selected anyway
+	var artifacts []client.Artifact
+	for _, repoPath := range repoPaths {
+		if !slices.Contains(exts, path.Ext(repoPath)) || !scope.ContainsRepoPath(repoPath) || !d.nameMatcher.Matches(client.Artifact{Name: path.Base(repoPath)}) {
+			continue
+		}
+		artifact, err := artifClient.GetArtifact(ctx, repoPath)
+		if err != nil {
+			return nil, err
+		}
+		artifacts = append(artifacts, artifact)
+	}
+	return artifacts, nil
+}
+
+// FilterByFileType keeps the artifacts with any of the extensions, which must have a leading '.'.
+func FilterByFileType(exts []string, artifacts []client.Artifact) []client.Artifact {
+	var filtered []client.Artifact
+	for _, artifact := range artifacts {
+		if slices.Contains(exts, path.Ext(artifact.Name)) {
+			filtered = append(filtered, artifact)
+		}
+	}
+	return filtered
+}
+
+// FilterByPreferredFileType keeps the artifacts with the first of the extensions, in order, that any artifact has.
+func FilterByPreferredFileType(exts []string, artifacts []client.Artifact) []client.Artifact {
+	for _, ext := range exts {
+		if preferred := FilterByFileType([]string{ext}, artifacts); len(preferred) > 0 {
+			return preferred
+		}
+	}
+	return nil
+}
+
+// FilterByProps keeps the artifacts that have every one of the 'key=value' properties.
+func FilterByProps(kvProps []string, artifacts []client.Artifact) []client.Artifact {
+	var filtered []client.Artifact
+	for _, artifact := range artifacts {
+		if hasAllProps(artifact, kvProps) {
+			filtered = append(filtered, artifact)
+		}
+	}
+	return filtered
+}
+
+func hasAllProps(artifact client.Artifact, kvProps []string) bool {
+	for _, kvProp := range kvProps {
+		key, value, _ := strings.Cut(kvProp, "=")
+		found := false
+		for _, have := range artifact.Properties[key] {
+			if have == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// createdBefore orders artifacts by creation date, falling back to comparing the raw dates if either can't be parsed.
+func createdBefore(a, b client.Artifact) bool {
+	aTime, aErr := a.CreatedTime()
+	bTime, bErr := b.CreatedTime()
+	if aErr == nil && bErr == nil {
+		return aTime.Before(bTime)
+	}
+	return a.Created < b.Created
+}
+
+// SortNewestFirst orders the artifacts by creation date, most recent first, and by path when the dates are equal.
+func SortNewestFirst(artifacts []client.Artifact) {
+	sort.SliceStable(artifacts, func(i, j int) bool {
+		if createdBefore(artifacts[j], artifacts[i]) {
+			return true
+		}
+		if createdBefore(artifacts[i], artifacts[j]) {
+			return false
+		}
+		return artifacts[i].RepoPath() < artifacts[j].RepoPath()
+	})
+}
+
+// Ways to choose between several matching artifacts, set with 'select'.
+const (
+	SelectLatestCreated  = "latest_created"
+	SelectLatestModified = "latest_modified"
+	SelectOldest         = "oldest"
+	SelectHighestSemver  = "highest_semver"
+	SelectUnique         = "unique"
+)
+
+var selectStrategies = []string{SelectLatestCreated, SelectLatestModified, SelectOldest, SelectHighestSemver, SelectUnique}
+
+// versionPattern finds a version such as 2.3, 2.3.1 or v2.3.1-rc.1 in a file name.
+var versionPattern = regexp.MustCompile(`v?\d+\.\d+(?:\.\d+)?(?:-[0-9A-Za-z.-]+)?(?:\+[0-9A-Za-z.-]+)?`)
+
+// ArtifactVersion returns the semantic version of the artifact, read from the property if one is named,
+// otherwise from the last version-like part of the file name (ex: win22-2.3.1.ova).
+func ArtifactVersion(artifact client.Artifact, versionProp string) (*version.Version, error) {
+	if versionProp != "" {
+		values := artifact.Properties[versionProp]
+		if len(values) == 0 {
+			return nil, fmt.Errorf("%s has no '%s' property", artifact.RepoPath(), versionProp)
+		}
+		return version.NewSemver(values[0])
+	}
+
+	found := versionPattern.FindAllString(ArtifactName(artifact), -1)
+	if len(found) == 0 {
+		return nil, fmt.Errorf("no version was found in the name of %s", artifact.RepoPath())
+	}
+	return version.NewSemver(found[len(found)-1])
+}
+
+// FilterByVersion keeps the artifacts whose version satisfies the constraints; artifacts without a version are dropped.
+func FilterByVersion(constraints version.Constraints, versionProp string, artifacts []client.Artifact) []client.Artifact {
+	var filtered []client.Artifact
+	for _, artifact := range artifacts {
+		v, err := ArtifactVersion(artifact, versionProp)
+		if err != nil {
+			log.Printf("[DEBUG] Skipping %s for the version constraint: %s", artifact.RepoPath(), err)
+			continue
+		}
+		if constraints.Check(v) {
+			filtered = append(filtered, artifact)
+		}
+	}
+	return filtered
+}
+
+// VersionMismatchError lists the versions that were found so the template author can see why none satisfied the constraint.
+func VersionMismatchError(constraint, versionProp string, artifacts []client.Artifact) error {
+	source := "file names"
+	if versionProp != "" {
+		source = "'" + versionProp + "' properties"
+	}
+
+	var found []string
+	for _, artifact := range artifacts {
+		if v, err := ArtifactVersion(artifact, versionProp); err == nil {
+			found = append(found, v.Original())
+		}
+	}
+	if len(found) == 0 {
+		return fmt.Errorf("%d artifact(s) matched the search, but no version could be read from their %s to check against version_constraint %q", len(artifacts), source, constraint)
+	}
+	sort.Strings(found)
+	return fmt.Errorf("%d artifact(s) matched the search, but none of the versions in their %s (%s) satisfy version_constraint %q", len(artifacts), source, strings.Join(found, ", "), constraint)
+}
+
+// SortArtifacts orders the artifacts so that the one the strategy selects comes first. Artifacts without a
+// usable version sort last for 'highest_semver', and those without a readable modification date for
+// 'latest_modified'.
+func SortArtifacts(strategy, versionProp string, artifacts []client.Artifact) {
+	SortNewestFirst(artifacts)
+
+	switch strategy {
+	case SelectLatestModified:
+		modified := map[string]time.Time{}
+		for _, artifact := range artifacts {
+			if t, err := artifact.ModifiedTime(); err == nil {
+				modified[artifact.RepoPath()] = t
+			}
+		}
+		sort.SliceStable(artifacts, func(i, j int) bool {
+			iTime, iOk := modified[artifacts[i].RepoPath()]
+			jTime, jOk := modified[artifacts[j].RepoPath()]
+			if !iOk || !jOk {
+				return iOk && !jOk
+			}
+			return iTime.After(jTime)
+		})
+	case SelectOldest:
+		sort.SliceStable(artifacts, func(i, j int) bool {
+			return createdBefore(artifacts[i], artifacts[j])
+		})
+	case SelectHighestSemver:
+		versions := map[string]*version.Version{}
+		for _, artifact := range artifacts {
+			if v, err := ArtifactVersion(artifact, versionProp); err == nil {
+				versions[artifact.RepoPath()] = v
+			}
+		}
+		sort.SliceStable(artifacts, func(i, j int) bool {
+			iVersion, jVersion := versions[artifacts[i].RepoPath()], versions[artifacts[j].RepoPath()]
+			if iVersion == nil || jVersion == nil {
+				return jVersion == nil && iVersion != nil
+			}
+			return iVersion.GreaterThan(jVersion)
+		})
+	}
+}
+
+// SelectArtifact orders the matches with SortArtifacts and returns the first. 'unique' fails when there is more
+// than one match, and 'highest_semver' fails when no match has a version.
+func SelectArtifact(strategy, versionProp string, artifacts []client.Artifact) (client.Artifact, error) {
+	SortArtifacts(strategy, versionProp, artifacts)
+	selected := artifacts[0]
+
+	switch strategy {
+	case SelectUnique:
+		if len(artifacts) > 1 {
+			var repoPaths []string
+			for _, artifact := range artifacts {
+				repoPaths = append(repoPaths, artifact.RepoPath())
+			}
+			return client.Artifact{}, fmt.Errorf("'select' is set to 'unique', but %d artifacts matched: %s", len(artifacts), strings.Join(repoPaths, ", "))
+		}
+	case SelectHighestSemver:
+		if _, err := ArtifactVersion(selected, versionProp); err != nil {
+			return client.Artifact{}, fmt.Errorf("'select' is set to 'highest_semver', but none of the %d matching artifacts has a semantic version: %s", len(artifacts), err)
+		}
+	}
+	return selected, nil
+}
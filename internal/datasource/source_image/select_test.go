@@ -0,0 +1,127 @@
+package artifactImage
+
+import (
+	"strings"
+	"testing"
+
+	"packer-plugin-artifactory/internal/client"
+
+	"github.com/hashicorp/go-version"
+)
+
+func TestSelectArtifact(t *testing.T) {
+	artifacts := func() []client.Artifact {
+		return []client.Artifact{
+			{Repo: "images", Name: "win22-1.10.0.ova", Created: "2024-03-01T00:00:00.000Z", Modified: "2024-03-01T00:00:00.000Z",
+				Properties: map[string][]string{"version": {"1.9.0"}}},
+			{Repo: "images", Name: "win22-1.9.2.ova", Created: "2024-02-01T00:00:00.000Z", Modified: "2024-04-01T00:00:00.000Z",
+				Properties: map[string][]string{"version": {"1.10.1"}}},
+			{Repo: "images", Name: "win22-1.2.0.ova", Created: "2024-01-01T00:00:00.000Z", Modified: "2024-01-01T00:00:00.000Z"},
+		}
+	}
+
+	tests := []struct {
+		strategy    string
+		versionProp string
+		artifacts   []client.Artifact
+		want        string
+		wantErr     string
+	}{
+		{strategy: SelectLatestCreated, artifacts: artifacts(), want: "win22-1.10.0.ova"},
+		{strategy: SelectLatestModified, artifacts: artifacts(), want: "win22-1.9.2.ova"},
+		{strategy: SelectLatestModified, artifacts: append([]client.Artifact{
+			{Repo: "images", Name: "win22-1.11.0.ova", Created: "2024-05-01T00:00:00.000Z", Modified: "yesterday"}}, artifacts()...), want: "win22-1.9.2.ova"},
+		{strategy: SelectOldest, artifacts: artifacts(), want: "win22-1.2.0.ova"},
+		{strategy: SelectHighestSemver, artifacts: artifacts(), want: "win22-1.10.0.ova"},
+		{strategy: SelectHighestSemver, versionProp: "version", artifacts: artifacts(), want: "win22-1.9.2.ova"},
+		{strategy: SelectHighestSemver, versionProp: "build", artifacts: artifacts(), wantErr: "none of the 3 matching artifacts has a semantic version"},
+		{strategy: SelectUnique, artifacts: artifacts(), wantErr: "3 artifacts matched"},
+		{strategy: SelectUnique, artifacts: artifacts()[:1], want: "win22-1.10.0.ova"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.strategy+"/"+tt.versionProp, func(t *testing.T) {
+			selected, err := SelectArtifact(tt.strategy, tt.versionProp, tt.artifacts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("SelectArtifact() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SelectArtifact() error = %s", err)
+			}
+			if selected.Name != tt.want {
+				t.Errorf("SelectArtifact() = %s, want %s", selected.Name, tt.want)
+			}
+			if tt.artifacts[0].Name != tt.want {
+				t.Errorf("expected the selected artifact to be sorted first, got %s", tt.artifacts[0].Name)
+			}
+		})
+	}
+}
+
+func TestArtifactVersion(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"win22-2.3.1.ova", "2.3.1"},
+		{"rhel9_v9.4.ova", "9.4.0"},
+		{"win22-2.3.1-rc.1.ova", "2.3.1-rc.1"},
+		{"win22.ova", ""},
+	}
+
+	for _, tt := range tests {
+		v, err := ArtifactVersion(client.Artifact{Repo: "images", Name: tt.name}, "")
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("ArtifactVersion(%s) = %s, want an error", tt.name, v)
+			}
+			continue
+		}
+		if err != nil || v.String() != tt.want {
+			t.Errorf("ArtifactVersion(%s) = %v, %v; want %s", tt.name, v, err, tt.want)
+		}
+	}
+}
+
+func TestFilterByVersion(t *testing.T) {
+	artifacts := []client.Artifact{
+		{Repo: "images", Name: "a.ova", Properties: map[string][]string{"version": {"2.2.9"}}},
+		{Repo: "images", Name: "b.ova", Properties: map[string][]string{"version": {"2.3.0"}}},
+		{Repo: "images", Name: "c.ova", Properties: map[string][]string{"version": {"2.10.4"}}},
+		{Repo: "images", Name: "d.ova", Properties: map[string][]string{"version": {"3.0.0"}}},
+		{Repo: "images", Name: "e.ova"},
+	}
+
+	tests := []struct {
+		constraint string
+		want       []string
+	}{
+		{">= 2.3, < 3.0", []string{"b.ova", "c.ova"}},
+		{"~> 2.2.0", []string{"a.ova"}},
+		{"= 3.0.0", []string{"d.ova"}},
+		{"> 4.0", nil},
+	}
+
+	for _, tt := range tests {
+		constraints, err := version.NewConstraint(tt.constraint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, artifact := range FilterByVersion(constraints, "version", artifacts) {
+			got = append(got, artifact.Name)
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("FilterByVersion(%q) = %v, want %v", tt.constraint, got, tt.want)
+		}
+	}
+
+	err := VersionMismatchError("> 4.0", "version", artifacts)
+	want := `5 artifact(s) matched the search, but none of the versions in their 'version' properties (2.10.4, 2.2.9, 2.3.0, 3.0.0) satisfy version_constraint "> 4.0"`
+	if err.Error() != want {
+		t.Errorf("VersionMismatchError() = %q, want %q", err, want)
+	}
+}
@@ -0,0 +1,305 @@
+// Package download fetches files from Artifactory for the datasources, checking each one against the checksum
+// Artifactory has for it before it is used.
+package download
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"packer-plugin-artifactory/internal/client"
+)
+
+// DefaultParallelism is how many files are downloaded at once unless the datasource says otherwise.
+const DefaultParallelism = 4
+
+// File is a file to download, along with where to save it.
+type File struct {
+	client.FileInfo
+	LocalPath string
+}
+
+// Options change how All downloads the files.
+type Options struct {
+	// How many files to download at once; defaults to DefaultParallelism
+	Parallelism int
+	// Leave files that are already at their LocalPath with the same size and checksum as in Artifactory
+	SkipExisting bool
+	// Where to report each download's progress, ex: a packersdk.Ui; defaults to a LogProgress
+	Progress client.ProgressTracker
+}
+
+// Result is the outcome of downloading one file.
+type Result struct {
+	File
+	// Bytes written to LocalPath
+	Written int64
+	// Whether the file was left as it was because it already matched Artifactory
+	Skipped bool
+	Err     error
+}
+
+// PartialSuffix is added to the name of a file while it is downloading. The file only gets its final name once
+// it has been verified, so a half-written file is never left under that name.
+const PartialSuffix = ".partial"
+
+// maxAttempts is how many times a download that keeps making progress is resumed before giving up.
+const maxAttempts = 3
+
+// Fetch downloads the file and verifies it. The size and checksums are looked up first, and filled in, if the
+// file came without them (ex: a name from a file list). Progress is reported to the tracker, if there is one.
+//
+// The file is written to LocalPath plus PartialSuffix and renamed to LocalPath once verified. An interrupted
+// download is resumed from where it stopped, both within this call and by the next call for the same file. A
+// file that fails verification is deleted; if it was a resumed download, it is downloaded once more from the
+// start first, in case the earlier part was the bad part.
+func Fetch(ctx context.Context, artifClient *client.Client, file *File, progress client.ProgressTracker) (int64, error) {
+	if err := describe(ctx, artifClient, file); err != nil {
+		return 0, err
+	}
+
+	partialPath := file.LocalPath + PartialSuffix
+	resumed := false
+	if info, err := os.Stat(partialPath); err == nil && info.Size() > 0 {
+		resumed = true
+	}
+
+	written, err := resume(ctx, artifClient, file.RepoPath, partialPath, progress)
+	if err == nil {
+		err = Verify(partialPath, file.FileInfo)
+		if err != nil && resumed {
+			log.Printf("[WARN] %s; downloading it again from the start", err)
+			removePartial(partialPath)
+			var again int64
+			again, err = resume(ctx, artifClient, file.RepoPath, partialPath, progress)
+			written += again
+			if err == nil {
+				err = Verify(partialPath, file.FileInfo)
+			}
+		}
+	}
+	if err != nil {
+		// Keep a partial download to resume next time, unless it's corrupt
+		var verifyErr *VerifyError
+		if errors.As(err, &verifyErr) {
+			removePartial(partialPath)
+			verifyErr.LocalPath = file.LocalPath
+		}
+		return written, err
+	}
+
+	if err := os.Rename(partialPath, file.LocalPath); err != nil {
+		return written, fmt.Errorf("Unable to move %s to %s: %s", partialPath, file.LocalPath, err)
+	}
+	return written, nil
+}
+
+// describe looks up the size and checksums of the file if it came without them.
+func describe(ctx context.Context, artifClient *client.Client, file *File) error {
+	if file.Sha256 != "" || file.Sha1 != "" {
+		return nil
+	}
+	info, err := artifClient.StatFile(ctx, file.RepoPath)
+	if err != nil {
+		return err
+	}
+	file.FileInfo = info
+	return nil
+}
+
+// Unchanged reports whether the file at localPath has the same size and checksum as the remote one. A file
+// Artifactory has no checksum for is never considered unchanged.
+func Unchanged(localPath string, remote client.FileInfo) bool {
+	info, err := os.Stat(localPath)
+	if err != nil || !info.Mode().IsRegular() || info.Size() != remote.Size {
+		return false
+	}
+	_, want, hasher := checksum(remote)
+	if hasher == nil {
+		return false
+	}
+	got, err := sum(localPath, hasher)
+	return err == nil && got == want
+}
+
+// resume downloads the rest of the file into partialPath, trying again as long as each attempt makes progress.
+func resume(ctx context.Context, artifClient *client.Client, repoPath, partialPath string, progress client.ProgressTracker) (int64, error) {
+	var total int64
+	for attempt := 1; ; attempt++ {
+		written, err := artifClient.Download(ctx, repoPath, partialPath, progress)
+		total += written
+		if err == nil || written == 0 || attempt == maxAttempts || ctx.Err() != nil {
+			return total, err
+		}
+		log.Printf("[WARN] %s; resuming (attempt %d of %d)", err, attempt+1, maxAttempts)
+	}
+}
+
+func removePartial(partialPath string) {
+	if err := os.Remove(partialPath); err != nil && !os.IsNotExist(err) {
+		log.Printf("[WARN] Unable to delete %s: %s", partialPath, err)
+	}
+}
+
+// checksum returns the algorithm to verify the file with and the expected value: sha256 when Artifactory has it,
+// otherwise sha1. Returns empty strings when Artifactory has neither.
+func checksum(remote client.FileInfo) (string, string, hash.Hash) {
+	if remote.Sha256 != "" {
+		return "sha256", strings.ToLower(remote.Sha256), sha256.New()
+	}
+	if remote.Sha1 != "" {
+		return "sha1", strings.ToLower(remote.Sha1), sha1.New()
+	}
+	return "", "", nil
+}
+
+// VerifyError is returned when a downloaded file doesn't match the checksum Artifactory has for it.
+type VerifyError struct {
+	Algorithm string
+	LocalPath string
+	RepoPath  string
+	Got, Want string
+}
+
+func (e *VerifyError) Error() string {
+	return fmt.Sprintf("The %s of %s is %s, but Artifactory has %s for %s; the download is corrupt and was deleted",
+		e.Algorithm, e.LocalPath, e.Got, e.Want, e.RepoPath)
+}
+
+// sum returns the hex checksum of the file at localPath.
+func sum(localPath string, hasher hash.Hash) (string, error) {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// LocalSha256 returns the sha256 of the file at localPath.
+func LocalSha256(localPath string) (string, error) {
+	got, err := sum(localPath, sha256.New())
+	if err != nil {
+		return "", fmt.Errorf("Unable to read %s: %s", localPath, err)
+	}
+	return got, nil
+}
+
+// Verify compares the local file with the checksum Artifactory has for the remote one.
+func Verify(localPath string, remote client.FileInfo) error {
+	algorithm, want, hasher := checksum(remote)
+	if hasher == nil {
+		log.Printf("[WARN] Artifactory has no checksum for %s; %s was not verified", remote.RepoPath, localPath)
+		return nil
+	}
+
+	got, err := sum(localPath, hasher)
+	if err != nil {
+		return fmt.Errorf("Unable to read %s to verify it: %s", localPath, err)
+	}
+	if got != want {
+		return &VerifyError{Algorithm: algorithm, LocalPath: localPath, RepoPath: remote.RepoPath, Got: got, Want: want}
+	}
+	log.Printf("Verified the %s of %s", algorithm, localPath)
+	return nil
+}
+
+// All downloads the files with up to opts.Parallelism downloads at once. Every file is attempted even if others
+// fail; the results are in the same order as the files.
+func All(ctx context.Context, artifClient *client.Client, files []File, opts Options) []Result {
+	results := make([]Result, len(files))
+	for i, file := range files {
+		results[i].File = file
+	}
+
+	if opts.Progress == nil {
+		opts.Progress = &LogProgress{}
+	}
+	parallelism := opts.Parallelism
+	if parallelism <= 0 {
+		parallelism = DefaultParallelism
+	}
+	if parallelism > len(files) {
+		parallelism = len(files)
+	}
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for worker := 0; worker < parallelism; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				fetch(ctx, artifClient, &results[i], opts)
+			}
+		}()
+	}
+	for i := range files {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	return results
+}
+
+// fetch downloads the file for All, or skips it if it is already there and opts allow that.
+func fetch(ctx context.Context, artifClient *client.Client, result *Result, opts Options) {
+	if opts.SkipExisting {
+		if result.Err = describe(ctx, artifClient, &result.File); result.Err != nil {
+			log.Printf("[ERROR] Failed to download %s: %s", result.RepoPath, result.Err)
+			return
+		}
+		if Unchanged(result.LocalPath, result.FileInfo) {
+			result.Skipped = true
+			log.Printf("Skipped %s; %s already matches it", result.RepoPath, result.LocalPath)
+			return
+		}
+	}
+
+	log.Println("Downloading: " + result.RepoPath)
+	result.Written, result.Err = Fetch(ctx, artifClient, &result.File, opts.Progress)
+	if result.Err != nil {
+		log.Printf("[ERROR] Failed to download %s: %s", result.RepoPath, result.Err)
+	} else {
+		log.Printf("Downloaded %s to %s (%d bytes)", result.RepoPath, result.LocalPath, result.Written)
+	}
+}
+
+// Skipped totals the files that were skipped because they already matched Artifactory, and their size.
+func Skipped(results []Result) (int, int64) {
+	var files int
+	var bytes int64
+	for _, result := range results {
+		if result.Skipped {
+			files++
+			bytes += result.Size
+		}
+	}
+	return files, bytes
+}
+
+// Error combines the failures into one error listing each of them, or returns nil if every file was downloaded.
+func Error(results []Result) error {
+	var failed []string
+	for _, result := range results {
+		if result.Err != nil {
+			failed = append(failed, "  "+result.RepoPath+": "+result.Err.Error())
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d of %d file(s) failed to download:\n%s", len(failed), len(results), strings.Join(failed, "\n"))
+}
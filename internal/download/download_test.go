@@ -0,0 +1,248 @@
+package download
+
+import (
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"packer-plugin-artifactory/internal/client"
+	"packer-plugin-artifactory/internal/testutil/fakeartifactory"
+)
+
+func TestFetch(t *testing.T) {
+	server := fakeartifactory.New(t)
+	item := server.AddArtifact("/generic/drivers/nic.zip", []byte("driver bundle"), nil)
+	artifClient := client.New(&client.ConnectionConfig{ArtifactoryToken: fakeartifactory.Token, ArtifactoryServer: server.ApiUrl()})
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	// Checksums are looked up when the file comes without them
+	file := File{FileInfo: client.FileInfo{RepoPath: item.RepoPath()}, LocalPath: filepath.Join(dir, "nic.zip")}
+	written, err := Fetch(ctx, artifClient, &file, nil)
+	if err != nil {
+		t.Fatalf("Fetch() error = %s", err)
+	}
+	if written != 13 || file.Sha256 != item.Sha256() || file.Size != 13 {
+		t.Errorf("Fetch() = %d, %+v", written, file)
+	}
+
+	// Only sha1
+	file = File{FileInfo: client.FileInfo{RepoPath: item.RepoPath(), Sha1: item.Sha1()}, LocalPath: filepath.Join(dir, "sha1.zip")}
+	if _, err := Fetch(ctx, artifClient, &file, nil); err != nil {
+		t.Errorf("Fetch() with sha1 error = %s", err)
+	}
+
+	tests := []struct {
+		name string
+		info client.FileInfo
+		want string
+	}{
+		{"sha256", client.FileInfo{RepoPath: item.RepoPath(), Sha256: strings.Repeat("0", 64), Sha1: item.Sha1()}, "The sha256 of"},
+		{"sha1", client.FileInfo{RepoPath: item.RepoPath(), Sha1: strings.Repeat("0", 40)}, "The sha1 of"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name+" mismatch", func(t *testing.T) {
+			file := File{FileInfo: tt.info, LocalPath: filepath.Join(dir, "corrupt.zip")}
+			_, err := Fetch(ctx, artifClient, &file, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.want) || !strings.Contains(err.Error(), "corrupt and was deleted") {
+				t.Fatalf("Fetch() error = %v, want %q", err, tt.want)
+			}
+			for _, leftover := range []string{file.LocalPath, file.LocalPath + PartialSuffix} {
+				if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+					t.Errorf("%s should have been deleted", leftover)
+				}
+			}
+		})
+	}
+}
+
+func TestFetch_Resume(t *testing.T) {
+	content := []byte("a multi-gigabyte disk, give or take")
+
+	tests := []struct {
+		name        string
+		partial     string
+		interrupts  int
+		wantRanges  []string
+		wantWritten int
+	}{
+		{name: "fresh", wantWritten: len(content)},
+		{name: "partial from an earlier run", partial: "a multi-", wantRanges: []string{"bytes=8-"}, wantWritten: len(content) - 8},
+		{name: "already complete", partial: string(content), wantRanges: []string{"bytes=35-"}, wantWritten: 0},
+		{name: "interrupted", interrupts: 2, wantRanges: []string{"bytes=10-", "bytes=20-"}, wantWritten: len(content)},
+		{name: "corrupt partial", partial: "A MULTI-", wantRanges: []string{"bytes=8-"}, wantWritten: len(content) - 8 + len(content)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := fakeartifactory.New(t)
+			item := server.AddArtifact("/images/win22/win22-disk1.vmdk", content, nil)
+			artifClient := client.New(&client.ConnectionConfig{ArtifactoryToken: fakeartifactory.Token, ArtifactoryServer: server.ApiUrl()})
+			file := File{FileInfo: client.FileInfo{RepoPath: item.RepoPath(), Sha256: item.Sha256()}, LocalPath: filepath.Join(t.TempDir(), "win22-disk1.vmdk")}
+			if tt.partial != "" {
+				if err := os.WriteFile(file.LocalPath+PartialSuffix, []byte(tt.partial), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			server.InterruptDownloads(tt.interrupts, 10)
+
+			written, err := Fetch(context.Background(), artifClient, &file, nil)
+			if err != nil {
+				t.Fatalf("Fetch() error = %s", err)
+			}
+			if got, _ := os.ReadFile(file.LocalPath); string(got) != string(content) {
+				t.Errorf("downloaded %q", got)
+			}
+			if _, err := os.Stat(file.LocalPath + PartialSuffix); !os.IsNotExist(err) {
+				t.Error("the partial file should have been renamed")
+			}
+			if written != int64(tt.wantWritten) {
+				t.Errorf("Fetch() wrote %d bytes, want %d", written, tt.wantWritten)
+			}
+			if got := server.Ranges(); !slices.Equal(got, tt.wantRanges) {
+				t.Errorf("Range requests = %v, want %v", got, tt.wantRanges)
+			}
+		})
+	}
+}
+
+func TestFetch_ResumeNextRun(t *testing.T) {
+	server := fakeartifactory.New(t)
+	item := server.AddArtifact("/images/win22/win22.ova", []byte("0123456789abcdefghij"), nil)
+	artifClient := client.New(&client.ConnectionConfig{ArtifactoryToken: fakeartifactory.Token, ArtifactoryServer: server.ApiUrl()})
+	file := File{FileInfo: client.FileInfo{RepoPath: item.RepoPath(), Sha256: item.Sha256()}, LocalPath: filepath.Join(t.TempDir(), "win22.ova")}
+
+	// Every attempt is cut off, so the file is left half-written, but never under its final name
+	server.InterruptDownloads(maxAttempts, 3)
+	if _, err := Fetch(context.Background(), artifClient, &file, nil); err == nil {
+		t.Fatal("Fetch() should fail when every attempt is interrupted")
+	}
+	if _, err := os.Stat(file.LocalPath); !os.IsNotExist(err) {
+		t.Errorf("%s should not exist until the download is verified", file.LocalPath)
+	}
+	if got, _ := os.ReadFile(file.LocalPath + PartialSuffix); string(got) != "012345678" {
+		t.Fatalf("partial file = %q", got)
+	}
+
+	written, err := Fetch(context.Background(), artifClient, &file, nil)
+	if err != nil {
+		t.Fatalf("Fetch() error = %s", err)
+	}
+	if got, _ := os.ReadFile(file.LocalPath); string(got) != "0123456789abcdefghij" || written != 11 {
+		t.Errorf("resumed download wrote %d bytes: %q", written, got)
+	}
+}
+
+func TestUnchanged(t *testing.T) {
+	localPath := filepath.Join(t.TempDir(), "win22.ova")
+	if err := os.WriteFile(localPath, []byte("image"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	item := &fakeartifactory.Item{Content: []byte("image")}
+	other := &fakeartifactory.Item{Content: []byte("IMAGE")}
+
+	tests := []struct {
+		name   string
+		remote client.FileInfo
+		want   bool
+	}{
+		{"sha256", client.FileInfo{Size: 5, Sha256: item.Sha256()}, true},
+		{"sha1 only", client.FileInfo{Size: 5, Sha1: item.Sha1()}, true},
+		{"different size", client.FileInfo{Size: 6, Sha256: item.Sha256()}, false},
+		{"different contents", client.FileInfo{Size: 5, Sha256: other.Sha256()}, false},
+		{"no checksum", client.FileInfo{Size: 5}, false},
+	}
+	for _, tt := range tests {
+		if got := Unchanged(localPath, tt.remote); got != tt.want {
+			t.Errorf("%s: Unchanged() = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+	if Unchanged(localPath+".missing", client.FileInfo{Size: 5, Sha256: item.Sha256()}) {
+		t.Error("a missing file is never unchanged")
+	}
+}
+
+func TestAllAndError(t *testing.T) {
+	server := fakeartifactory.New(t)
+	server.AddArtifact("/generic/a.txt", []byte("a"), nil)
+	server.AddArtifact("/generic/b.txt", []byte("b"), nil)
+	artifClient := client.New(&client.ConnectionConfig{ArtifactoryToken: fakeartifactory.Token, ArtifactoryServer: server.ApiUrl()})
+	dir := t.TempDir()
+
+	var files []File
+	for _, name := range []string{"a.txt", "missing.txt", "b.txt"} {
+		files = append(files, File{FileInfo: client.FileInfo{RepoPath: "/generic/" + name}, LocalPath: filepath.Join(dir, name)})
+	}
+	results := All(context.Background(), artifClient, files, Options{Parallelism: 2})
+
+	if results[0].Err != nil || results[2].Err != nil || results[1].Err == nil {
+		t.Errorf("All() = %+v, want only missing.txt to fail", results)
+	}
+	err := Error(results)
+	if err == nil || !strings.Contains(err.Error(), "1 of 3 file(s)") || !strings.Contains(err.Error(), "/generic/missing.txt") {
+		t.Errorf("Error() = %v", err)
+	}
+	if Error(results[:1]) != nil {
+		t.Error("Error() should be nil when every file was downloaded")
+	}
+}
+
+func TestImageFiles(t *testing.T) {
+	server := fakeartifactory.New(t)
+	for _, repoPath := range []string{
+		"/images/win22/win22.ova",
+		"/images/win22/win22.ovf", "/images/win22/win22.mf", "/images/win22/win22-disk1.vmdk", "/images/win22/win22-disk2.vmdk",
+		"/images/win22/Win22.vmtx", "/images/win22/Win22.nvram", "/images/win22/Win22.vmdk", "/images/win22/Win22_1.vmdk",
+		"/images/win22/Win22-flat.vmdk", "/images/win22/vmware.log",
+		"/images/win22/win22-core.ova", "/images/win22/readme.txt",
+	} {
+		server.AddArtifact(repoPath, []byte(repoPath), nil)
+	}
+	artifClient := client.New(&client.ConnectionConfig{ArtifactoryToken: fakeartifactory.Token, ArtifactoryServer: server.ApiUrl()})
+	outputDir := t.TempDir()
+
+	tests := []struct {
+		repoPath string
+		want     []string
+		wantErr  string
+	}{
+		{repoPath: "/images/win22/win22.ova", want: []string{"win22.ova"}},
+		{repoPath: "/images/win22/win22.ovf", want: []string{"win22-disk1.vmdk", "win22-disk2.vmdk", "win22.mf", "win22.ovf"}},
+		{repoPath: "/images/win22/Win22.vmtx", want: []string{"Win22-flat.vmdk", "Win22.nvram", "Win22.vmdk", "Win22.vmtx", "Win22_1.vmdk", "vmware.log"}},
+		{repoPath: "/images/win22/win23.ova", wantErr: "was not found"},
+		{repoPath: "/images/win22/readme.txt", wantErr: "is not an OVA, OVF, or VMTX image"},
+	}
+	for _, tt := range tests {
+		t.Run(path.Base(tt.repoPath), func(t *testing.T) {
+			files, err := ImageFiles(context.Background(), artifClient, tt.repoPath, outputDir)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ImageFiles() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ImageFiles() error = %s", err)
+			}
+
+			var names []string
+			imageName := strings.TrimSuffix(path.Base(tt.repoPath), path.Ext(tt.repoPath))
+			for _, file := range files {
+				names = append(names, path.Base(file.RepoPath))
+				if want := filepath.Join(outputDir, imageName, path.Base(file.RepoPath)); file.LocalPath != want {
+					t.Errorf("LocalPath = %s, want %s", file.LocalPath, want)
+				}
+				if file.Sha256 == "" {
+					t.Errorf("%s has no checksum", file.RepoPath)
+				}
+			}
+			slices.Sort(names)
+			if !slices.Equal(names, tt.want) {
+				t.Errorf("ImageFiles() = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package download
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"packer-plugin-artifactory/internal/client"
+)
+
+// imageFilePatterns lists the files that make up each image type, given the image name (the file name without
+// its extension). These are the files the artifactory-go-sdk downloaded, with two differences: vmware.log is
+// included with a VMTX image, and names are compared without regard to case, where the SDK lowercased the
+// names and compared the extensions exactly.
+var imageFilePatterns = map[string][]string{
+	".ova": {`^NAME\.ova$`},
+	".ovf": {`^NAME\.(ovf|mf)$`, `^NAME-disk\d+\.vmdk$`},
+	".vmtx": {
+		`^NAME\.(nvram|vmsd|vmtx|vmxf|vmdk)$`,
+		`^NAME_\d+\.vmdk$`,
+		`^NAME(_\d+)?-(ctk|flat)\.vmdk$`,
+		`^vmware\.log$`,
+	},
+}
+
+// isImageFile reports whether the file name belongs to the image.
+func isImageFile(ext, imageName, fileName string) bool {
+	for _, pattern := range imageFilePatterns[ext] {
+		pattern = "(?i)" + strings.ReplaceAll(pattern, "NAME", regexp.QuoteMeta(imageName))
+		if regexp.MustCompile(pattern).MatchString(fileName) {
+			return true
+		}
+	}
+	return false
+}
+
+// ImageFiles returns the files that make up the OVA, OVF, or VMTX image at the /repo/folder/file path: the image
+// file itself, plus the manifest and disks for OVF, or the VM files and disks for VMTX. They are saved under
+// outputDir, in a folder named after the image.
+func ImageFiles(ctx context.Context, artifClient *client.Client, repoPath, outputDir string) ([]File, error) {
+	fileName := path.Base(repoPath)
+	ext := strings.ToLower(path.Ext(fileName))
+	if _, ok := imageFilePatterns[ext]; !ok {
+		return nil, fmt.Errorf("%s is not an OVA, OVF, or VMTX image", repoPath)
+	}
+	imageName := strings.TrimSuffix(fileName, path.Ext(fileName))
+
+	listed, err := artifClient.ListFiles(ctx, path.Dir(repoPath), false)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []File
+	found := false
+	for _, info := range listed {
+		name := path.Base(info.RepoPath)
+		if !isImageFile(ext, imageName, name) {
+			continue
+		}
+		found = found || name == fileName
+		files = append(files, File{FileInfo: info, LocalPath: filepath.Join(outputDir, imageName, name)})
+	}
+	if !found {
+		return nil, fmt.Errorf("%s was not found in Artifactory", repoPath)
+	}
+	return files, nil
+}
@@ -0,0 +1,139 @@
+package download
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+)
+
+// DefaultProgressInterval is how often LogProgress logs each download's progress unless told otherwise.
+const DefaultProgressInterval = 10 * time.Second
+
+// LogProgress reports download progress (bytes, rate, and time left) as periodic log lines. It is what downloads
+// fall back to when there is no Packer UI to draw progress bars on; datasources aren't given one.
+type LogProgress struct {
+	// How often to log each download's progress; defaults to DefaultProgressInterval
+	Interval time.Duration
+}
+
+// TrackProgress wraps the download's body so reading it logs progress. currentSize is how much of the file was
+// already downloaded (ex: when resuming), and totalSize is -1 if the size is unknown.
+func (p *LogProgress) TrackProgress(src string, currentSize, totalSize int64, stream io.ReadCloser) io.ReadCloser {
+	interval := p.Interval
+	if interval <= 0 {
+		interval = DefaultProgressInterval
+	}
+	now := time.Now()
+	return &progressReader{
+		ReadCloser: stream,
+		src:        src,
+		interval:   interval,
+		start:      now,
+		logged:     now,
+		done:       currentSize,
+		resumedAt:  currentSize,
+		total:      totalSize,
+	}
+}
+
+type progressReader struct {
+	io.ReadCloser
+	src      string
+	interval time.Duration
+
+	mu        sync.Mutex
+	start     time.Time
+	logged    time.Time
+	done      int64
+	resumedAt int64
+	total     int64
+	finished  bool
+}
+
+// Read logs the progress whenever the interval has passed, and once more when the download is complete, so the
+// last line is never stale however fast the download was.
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.done += int64(n)
+	now := time.Now()
+	switch {
+	case err == io.EOF:
+		r.finish(now, true)
+	case now.Sub(r.logged) >= r.interval:
+		r.logged = now
+		log.Println(r.status(now))
+	}
+	return n, err
+}
+
+// Close logs where the download stopped, if it was closed before it was complete.
+func (r *progressReader) Close() error {
+	r.mu.Lock()
+	r.finish(time.Now(), false)
+	r.mu.Unlock()
+	return r.ReadCloser.Close()
+}
+
+// finish logs the final line, once: how long the download took, or where it stopped if it isn't complete.
+func (r *progressReader) finish(now time.Time, complete bool) {
+	if r.finished {
+		return
+	}
+	r.finished = true
+	if complete {
+		log.Printf("Downloaded %s: %s, %s/s, in %s", r.src, r.amount(), FormatBytes(int64(r.rate(now))), now.Sub(r.start).Round(time.Second))
+	} else {
+		log.Printf("Stopped downloading %s: %s, %s/s", r.src, r.amount(), FormatBytes(int64(r.rate(now))))
+	}
+}
+
+// status describes the progress so far, ex: "Downloading /repo/win22.ova: 1.2 GiB of 4.0 GiB (30%), 52.4 MiB/s,
+// about 55s left".
+func (r *progressReader) status(now time.Time) string {
+	rate := r.rate(now)
+	if r.total <= 0 {
+		return fmt.Sprintf("Downloading %s: %s, %s/s", r.src, r.amount(), FormatBytes(int64(rate)))
+	}
+	left := "time left unknown"
+	if rate > 0 {
+		eta := time.Duration(float64(r.total-r.done) / rate * float64(time.Second))
+		left = fmt.Sprintf("about %s left", eta.Round(time.Second))
+	}
+	return fmt.Sprintf("Downloading %s: %s, %s/s, %s", r.src, r.amount(), FormatBytes(int64(rate)), left)
+}
+
+// amount is how much was downloaded, ex: "1.2 GiB of 4.0 GiB (30%)", or "1.2 GiB" if the size is unknown.
+func (r *progressReader) amount() string {
+	if r.total <= 0 {
+		return FormatBytes(r.done)
+	}
+	return fmt.Sprintf("%s of %s (%d%%)", FormatBytes(r.done), FormatBytes(r.total), r.done*100/r.total)
+}
+
+// rate is in bytes per second, and only counts what was downloaded this time, not what was resumed from.
+func (r *progressReader) rate(now time.Time) float64 {
+	elapsed := now.Sub(r.start).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(r.done-r.resumedAt) / elapsed
+}
+
+// FormatBytes renders a size in bytes for people, ex: 512 B, 1.5 KiB, 4.0 GiB.
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,83 @@
+package download
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatBytes(t *testing.T) {
+	tests := map[int64]string{
+		0:                      "0 B",
+		512:                    "512 B",
+		1536:                   "1.5 KiB",
+		5 * 1024 * 1024:        "5.0 MiB",
+		4*1024*1024*1024 + 1e8: "4.1 GiB",
+	}
+	for n, want := range tests {
+		if got := FormatBytes(n); got != want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestLogProgress(t *testing.T) {
+	var logs bytes.Buffer
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(&logs)
+
+	tests := []struct {
+		name     string
+		interval time.Duration
+		read     int64
+		want     []string
+	}{
+		{name: "every read", interval: time.Nanosecond, read: -1,
+			want: []string{"Downloading /images/win22/win22.ova: 4.0 KiB of 4.0 KiB (100%), ", "Downloaded /images/win22/win22.ova: 4.0 KiB of 4.0 KiB (100%), "}},
+		// Too quick for the interval to pass, but the completion is still logged
+		{name: "long interval", interval: time.Hour, read: -1,
+			want: []string{"Downloaded /images/win22/win22.ova: 4.0 KiB of 4.0 KiB (100%), "}},
+		{name: "closed early", interval: time.Hour, read: 1024,
+			want: []string{"Stopped downloading /images/win22/win22.ova: 2.0 KiB of 4.0 KiB (50%), "}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs.Reset()
+			stream := io.NopCloser(strings.NewReader(strings.Repeat("x", 3072)))
+			body := (&LogProgress{Interval: tt.interval}).TrackProgress("/images/win22/win22.ova", 1024, 4096, stream)
+			var err error
+			if tt.read < 0 {
+				_, err = io.Copy(io.Discard, body)
+			} else {
+				_, err = io.CopyN(io.Discard, body, tt.read)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			body.Close()
+			body.Close()
+
+			lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+			if len(lines) != len(tt.want) {
+				t.Fatalf("logged %d lines, want %d:\n%s", len(lines), len(tt.want), logs.String())
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(lines[i], want) {
+					t.Errorf("progress line = %q, want it to include %q", lines[i], want)
+				}
+			}
+		})
+	}
+
+	reader := &progressReader{src: "tools.zip", start: time.Unix(0, 0), done: 3 * 1024 * 1024, resumedAt: 1024 * 1024, total: 5 * 1024 * 1024}
+	if got, want := reader.status(time.Unix(2, 0)), "Downloading tools.zip: 3.0 MiB of 5.0 MiB (60%), 1.0 MiB/s, about 2s left"; got != want {
+		t.Errorf("status() = %q, want %q", got, want)
+	}
+	reader.total = -1
+	if got, want := reader.status(time.Unix(2, 0)), "Downloading tools.zip: 3.0 MiB, 1.0 MiB/s"; got != want {
+		t.Errorf("status() with an unknown size = %q, want %q", got, want)
+	}
+}
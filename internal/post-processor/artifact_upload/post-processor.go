@@ -5,18 +5,17 @@ import (
 	"context"
 	"errors"
 	"log"
-	"os"
+
+	"packer-plugin-artifactory/internal/client"
 
 	"github.com/hashicorp/hcl/v2/hcldec"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	"github.com/hashicorp/packer-plugin-sdk/template/config"
-	artifactorysdk "github.com/raynaluzier/artifactory-go-sdk/common"
-	"github.com/raynaluzier/artifactory-go-sdk/tasks"
 )
 
 type Config struct {
-	ArtifactoryToken       string `mapstructure:"artifactory_token" required:"true"`
-	ArtifactoryServer      string `mapstructure:"artifactory_server" required:"true"`
+	client.ConnectionConfig `mapstructure:",squash"`
+
 	SourcePath			   string `mapstructure:"source_path" required:"true"`
 	// If not provided, then can reference an existing artifact URI to parse for the target
 	TargetPath			   string `mapstructure:"target_path" required:"false"`  // either this or existing uri target
@@ -39,72 +38,52 @@ func (p *PostProcessor) Configure(raws ...interface{}) error {
 		return err
 	}
 
-	if p.config.ArtifactoryToken == "" {
-		token := os.Getenv("ARTIFACTORY_TOKEN")
-		if token == "" {
-			log.Fatal("---> Missing Artifactory identity token. The token is required to complete tasks against Artifactory.")
-		}	
-	}
-
-	if p.config.ArtifactoryServer == "" {
-		serverApi := os.Getenv("ARTIFACTORY_SERVER")
-		if serverApi == "" {
-			log.Fatal("---> Missing Artifactory server API address. The server API address is required to communicate with Artifactory.")	
-		}
-	}
+	var errs *packersdk.MultiError
+	errs = packersdk.MultiErrorAppend(errs, p.config.ConnectionConfig.Prepare()...)
 
 	if p.config.SourcePath == "" {
-		log.Println("---> Please provide the source path to the artifact to upload.")
-		log.Fatal("Source path should be in the form of either 'h:\\lab\\' or '/lab/'")
+		errs = packersdk.MultiErrorAppend(errs, errors.New("Please provide the 'source_path' to the artifact to upload. "+
+			"Source path should be in the form of either 'h:\\lab\\' or '/lab/'"))
 	}
 
 	if p.config.TargetPath == "" && p.config.ExistingUriTarget == "" {
-		log.Println("---> Please provide either a target path OR use the 'existing_artifact_uri' input (which can be populated manually or from data source) to reference as a target location.")
-		log.Println("If using an existing artifact URI, the artifact's path will be parsed and used as the target for the new artifact.")
-		log.Fatal("Otherwise, the target path should be in the form of '/repo/folder/path.")
+		errs = packersdk.MultiErrorAppend(errs, errors.New("Please provide either a 'target_path' OR use the 'existing_uri_target' input (which can be populated manually or from data source) to reference as a target location. "+
+			"If using an existing artifact URI, the artifact's path will be parsed and used as the target for the new artifact. "+
+			"Otherwise, the target path should be in the form of '/repo/folder/path."))
 	}
 
 	if p.config.TargetPath != "" && p.config.ExistingUriTarget != "" {
-		log.Println("Values have been provided for both the target path AND an existing URI target.")
-		log.Println("The value provided in the target path will be used.")
+		errs = packersdk.MultiErrorAppend(errs, errors.New("Please provide only one of 'target_path' OR 'existing_uri_target'; "+
+			"both were set, so the upload location is ambiguous."))
 	}
 
 	if p.config.ImageType == "" {
-		log.Fatal("---> Please provide the image file type that will be uploaded: 'ova', 'ovf', or 'vmtx'.")
+		errs = packersdk.MultiErrorAppend(errs, errors.New("Please provide the 'image_type' that will be uploaded: 'ova', 'ovf', or 'vmtx'."))
 	}
 
 	if p.config.ImageName == "" {
-		log.Fatal("---> Please provide the name of the image; examples: win2022, rhel9, win22_25_01_25...")
+		errs = packersdk.MultiErrorAppend(errs, errors.New("Please provide the 'image_name' of the image; examples: win2022, rhel9, win22_25_01_25..."))
 	}
 
+	if len(errs.Errors) > 0 {
+		return errs
+	}
 	return nil
 }
 
 func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, source packersdk.Artifact) (packersdk.Artifact, bool, bool, error) {
-	var token, serverApi, sourcePath, targetPath, imageType, imageName string
+	var sourcePath, targetPath, imageType, imageName string
 
-	if p.config.ArtifactoryToken == "" {
-		token = os.Getenv("ARTIFACTORY_TOKEN")
-	} else {
-		token = p.config.ArtifactoryToken
-	}
-	
-	if p.config.ArtifactoryServer == "" {
-		serverApi = os.Getenv("ARTIFACTORY_SERVER")
-	} else {
-		serverApi = p.config.ArtifactoryServer
-	}
+	artifClient := client.New(&p.config.ConnectionConfig)
 
 	if p.config.SourcePath != "" {
 		sourcePath = p.config.SourcePath
 	}
 
-	if p.config.TargetPath != "" && p.config.ExistingUriTarget == "" {
-		targetPath = p.config.TargetPath
-	} else if p.config.TargetPath != "" && p.config.ExistingUriTarget != "" {
+	if p.config.TargetPath != "" {
 		targetPath = p.config.TargetPath
-	} else if p.config.TargetPath == "" && p.config.ExistingUriTarget != "" {
-		targetPath = artifactorysdk.ParseArtifUriForPath(serverApi, p.config.ExistingUriTarget)
+	} else if p.config.ExistingUriTarget != "" {
+		targetPath = artifClient.ParseArtifUriForPath(p.config.ExistingUriTarget)
 	}
 
 	if p.config.ImageType != "" {
@@ -115,14 +94,14 @@ func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, source
 		imageName = p.config.ImageName
 	}
 
-	log.Println("Server Address: " + serverApi)
+	log.Println("Server Address: " + artifClient.ServerApi)
 	log.Println("Image Name: " + imageName)
 	log.Println("Image Type: " + imageType)
 	log.Println("Source Path: " + sourcePath)
 	log.Println("Target Path: " + targetPath)
 
 	log.Println("Preparing to check and upload image artifact(s)...")
-	result := tasks.UploadArtifacts(serverApi, token, imageType, imageName, sourcePath, targetPath)
+	result := artifClient.UploadArtifacts(imageType, imageName, sourcePath, targetPath)
 
 	if result != "End of upload process" {
 		log.Println("Unable to upload artifacts - " + result)
@@ -0,0 +1,143 @@
+package artifactUpload
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"packer-plugin-artifactory/internal/testutil/fakeartifactory"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestPostProcessorConfigure_ReportsAllErrors(t *testing.T) {
+	t.Setenv("ARTIFACTORY_TOKEN", "")
+	t.Setenv("ARTIFACTORY_SERVER", "")
+
+	p := &PostProcessor{}
+	err := p.Configure(map[string]interface{}{})
+	if err == nil {
+		t.Fatal("expected an error for the missing settings")
+	}
+
+	multiErr, ok := err.(*packersdk.MultiError)
+	if !ok {
+		t.Fatalf("expected a *packer.MultiError, got %T", err)
+	}
+	// token, server, source_path, target_path/existing_uri_target, image_type, image_name
+	if len(multiErr.Errors) != 6 {
+		t.Errorf("expected 6 errors, got %d: %s", len(multiErr.Errors), err)
+	}
+	for _, field := range []string{"source_path", "target_path", "existing_uri_target", "image_type", "image_name"} {
+		if !strings.Contains(err.Error(), field) {
+			t.Errorf("expected %q to be reported, got: %s", field, err)
+		}
+	}
+}
+
+func TestPostProcessorConfigure_TargetConflict(t *testing.T) {
+	t.Setenv("ARTIFACTORY_TOKEN", "")
+	t.Setenv("ARTIFACTORY_SERVER", "")
+
+	p := &PostProcessor{}
+	err := p.Configure(map[string]interface{}{
+		"target_path":         "/images/",
+		"existing_uri_target": "https://server.com/artifactory/api/storage/repo/win22/win22.ova",
+		"image_type":          "ova",
+	})
+	multiErr, ok := err.(*packersdk.MultiError)
+	if !ok {
+		t.Fatalf("expected a *packer.MultiError, got %T: %v", err, err)
+	}
+	// token, server, source_path, the target conflict, image_name
+	if len(multiErr.Errors) != 5 {
+		t.Errorf("expected 5 errors, got %d: %s", len(multiErr.Errors), err)
+	}
+	if !strings.Contains(err.Error(), "only one of 'target_path' OR 'existing_uri_target'") {
+		t.Errorf("expected the target conflict to be reported, got: %s", err)
+	}
+	for _, field := range []string{"source_path", "image_name"} {
+		if !strings.Contains(err.Error(), field) {
+			t.Errorf("expected %q to be reported, got: %s", field, err)
+		}
+	}
+}
+
+func TestPostProcessorConfigure_Valid(t *testing.T) {
+	p := &PostProcessor{}
+	err := p.Configure(map[string]interface{}{
+		"artifactory_token":   "token",
+		"artifactory_server":  "https://server.com/artifactory/api",
+		"source_path":         "/lab/win22/",
+		"existing_uri_target": "https://server.com/artifactory/api/storage/repo/win22/win22.ova",
+		"image_type":          "ova",
+		"image_name":          "win22",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func TestPostProcess(t *testing.T) {
+	server := fakeartifactory.New(t)
+	server.AddArtifact("/images/win22/win22-old.ova", []byte("old"), nil)
+
+	sourceDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(sourceDir, "win22.ova"), []byte("ova"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		config   map[string]interface{}
+		wantPath string
+		wantErr  bool
+	}{
+		{
+			name:     "target path",
+			config:   map[string]interface{}{"target_path": "/images/", "image_type": "ova", "image_name": "win22"},
+			wantPath: "/images/win22/win22.ova",
+		},
+		{
+			name:     "existing artifact as target",
+			config:   map[string]interface{}{"existing_uri_target": server.StorageUrl("/images/win22/win22-old.ova"), "image_type": "ova", "image_name": "win22"},
+			wantPath: "/images/win22/win22/win22.ova",
+		},
+		{
+			name:    "missing source file",
+			config:  map[string]interface{}{"target_path": "/images/", "image_type": "ova", "image_name": "rhel9"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config["artifactory_token"] = fakeartifactory.Token
+			tt.config["artifactory_server"] = server.ApiUrl()
+			tt.config["source_path"] = sourceDir
+
+			p := &PostProcessor{}
+			if err := p.Configure(tt.config); err != nil {
+				t.Fatalf("Configure() error = %s", err)
+			}
+			_, keep, _, err := p.PostProcess(context.Background(), packersdk.TestUi(t), &packersdk.MockArtifact{})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PostProcess() error = %s", err)
+			}
+			if !keep {
+				t.Error("expected the artifact to be kept")
+			}
+			if _, ok := server.Artifact(tt.wantPath); !ok {
+				t.Errorf("expected %s to be uploaded, requests: %v", tt.wantPath, server.Requests())
+			}
+		})
+	}
+}
@@ -3,18 +3,20 @@ package artifactUpdateProps
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
-	"os"
+
+	"packer-plugin-artifactory/internal/client"
 
 	"github.com/hashicorp/hcl/v2/hcldec"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	"github.com/hashicorp/packer-plugin-sdk/template/config"
-	"github.com/raynaluzier/artifactory-go-sdk/tasks"
 )
 
 type Config struct {
-	ArtifactoryToken       string `mapstructure:"artifactory_token" required:"true"`
-	ArtifactoryServer      string `mapstructure:"artifactory_server" required:"true"`
+	client.ConnectionConfig `mapstructure:",squash"`
+
 	ArtifactUri			   string `mapstructure:"artifact_uri" required:"true"`
 	ArtifactProperties	   map[string]string `mapstructure:"properties" required:"true"`
 }
@@ -31,22 +33,20 @@ func (p *PostProcessor) Configure(raws ...interface{}) error {
 		return err
 	}
 
-	if p.config.ArtifactoryToken == "" {
-		log.Fatal("---> Missing Artifactory identity token. The token is required to complete tasks against Artifactory.")
-	}
-
-	if p.config.ArtifactoryServer == "" {
-		log.Fatal("---> Missing Artifactory server API address. The server API address is required to communicate with Artifactory.")
-	}
+	var errs *packersdk.MultiError
+	errs = packersdk.MultiErrorAppend(errs, p.config.ConnectionConfig.Prepare()...)
 
 	if p.config.ArtifactUri == "" {
-		log.Fatal("---> Missing Artifact URI. The Artifact URI is required to update the artifact's properties.")
+		errs = packersdk.MultiErrorAppend(errs, errors.New("Missing 'artifact_uri'. The Artifact URI is required to update the artifact's properties."))
 	}
 
 	if len(p.config.ArtifactProperties) == 0 {
-		log.Fatal("---> Missing Artifact properties. At least one key/value pair is required to update the artifact's properties.")
+		errs = packersdk.MultiErrorAppend(errs, errors.New("Missing artifact 'properties'. At least one key/value pair is required to update the artifact's properties."))
 	}
 
+	if len(errs.Errors) > 0 {
+		return errs
+	}
 	return nil
 }
 
@@ -63,19 +63,9 @@ func BuildProps(kvInput map[string]string) ([]string) {
 
 func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, source packersdk.Artifact) (packersdk.Artifact, bool, bool, error) {
 	var kvProperties []string
-	var token, serverApi, artifactUri string
+	var artifactUri string
 
-	if p.config.ArtifactoryToken == "" {
-		token = os.Getenv("ARTIFACTORY_TOKEN")
-	} else {
-		token = p.config.ArtifactoryToken
-	}
-	
-	if p.config.ArtifactoryServer == "" {
-		serverApi = os.Getenv("ARTIFACTORY_SERVER")
-	} else {
-		serverApi = p.config.ArtifactoryServer
-	}
+	artifClient := client.New(&p.config.ConnectionConfig)
 
 	if p.config.ArtifactUri != "" {
 		artifactUri = p.config.ArtifactUri
@@ -85,11 +75,12 @@ func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, source
 		kvProperties = BuildProps(p.config.ArtifactProperties)
 	}
 
-	statusCode, err := tasks.SetProps(serverApi, token, artifactUri, kvProperties)
+	statusCode, err := artifClient.SetProps(artifactUri, kvProperties)
 	if statusCode == "204" {
 		ui.Say("Property assignment to artifact was successful.")
 	} else {
-		log.Fatal("Unable to update the artifact properties - ", err)
+		log.Println("Unable to update the artifact properties - ", err)
+		return source, false, false, fmt.Errorf("Unable to update the artifact properties: %v", err)
 	}
 
 	return source, true, true, nil
@@ -0,0 +1,120 @@
+package artifactUpdateProps
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"packer-plugin-artifactory/internal/testutil/fakeartifactory"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestPostProcessorConfigure(t *testing.T) {
+	t.Setenv("ARTIFACTORY_TOKEN", "")
+	t.Setenv("ARTIFACTORY_SERVER", "")
+
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		wantErr []string
+	}{
+		{
+			name: "valid",
+			config: map[string]interface{}{
+				"artifactory_token":  "token",
+				"artifactory_server": "https://server.com/artifactory/api",
+				"artifact_uri":       "https://server.com/artifactory/api/storage/repo/win22/win22.ova",
+				"properties":         map[string]string{"release": "stable"},
+			},
+		},
+		{
+			name:    "missing everything",
+			config:  map[string]interface{}{},
+			wantErr: []string{"artifactory_token", "artifactory_server", "artifact_uri", "properties"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &PostProcessor{}
+			err := p.Configure(tt.config)
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			for _, field := range tt.wantErr {
+				if !strings.Contains(err.Error(), field) {
+					t.Errorf("expected %q to be reported, got: %s", field, err)
+				}
+			}
+		})
+	}
+}
+
+func TestPostProcess(t *testing.T) {
+	server := fakeartifactory.New(t)
+	server.AddArtifact("/images/win22/win22.ova", []byte("ova"), map[string]string{"release": "testing"})
+
+	tests := []struct {
+		name      string
+		uri       string
+		props     map[string]string
+		wantProps map[string]string
+		wantErr   bool
+	}{
+		{
+			name:      "add and replace properties",
+			uri:       server.StorageUrl("/images/win22/win22.ova"),
+			props:     map[string]string{"release": "stable", "testing": "passed"},
+			wantProps: map[string]string{"release": "stable", "testing": "passed"},
+		},
+		{
+			name:    "missing artifact",
+			uri:     server.StorageUrl("/images/rhel9/rhel9.ova"),
+			props:   map[string]string{"release": "stable"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &PostProcessor{}
+			err := p.Configure(map[string]interface{}{
+				"artifactory_token":  fakeartifactory.Token,
+				"artifactory_server": server.ApiUrl(),
+				"artifact_uri":       tt.uri,
+				"properties":         tt.props,
+			})
+			if err != nil {
+				t.Fatalf("Configure() error = %s", err)
+			}
+
+			_, keep, _, err := p.PostProcess(context.Background(), packersdk.TestUi(t), &packersdk.MockArtifact{})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PostProcess() error = %s", err)
+			}
+			if !keep {
+				t.Error("expected the artifact to be kept")
+			}
+
+			item, _ := server.Artifact("/images/win22/win22.ova")
+			for key, want := range tt.wantProps {
+				if got := item.Properties[key]; len(got) != 1 || got[0] != want {
+					t.Errorf("property %s = %v, want %q", key, got, want)
+				}
+			}
+		})
+	}
+}
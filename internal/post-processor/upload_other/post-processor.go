@@ -5,17 +5,17 @@ import (
 	"context"
 	"errors"
 	"log"
-	"os"
+
+	"packer-plugin-artifactory/internal/client"
 
 	"github.com/hashicorp/hcl/v2/hcldec"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	"github.com/hashicorp/packer-plugin-sdk/template/config"
-	"github.com/raynaluzier/artifactory-go-sdk/tasks"
 )
 
 type Config struct {
-	ArtifactoryToken       string `mapstructure:"artifactory_token" required:"true"`
-	ArtifactoryServer      string `mapstructure:"artifactory_server" required:"true"`
+	client.ConnectionConfig `mapstructure:",squash"`
+
 	SourcePath			   string `mapstructure:"source_path" required:"true"`
 	ArtifactoryPath		   string `mapstructure:"artifactory_path" required:"true"`
 	FileList         	   []string `mapstructure:"file_list" required:"true"`
@@ -33,52 +33,34 @@ func (p *PostProcessor) Configure(raws ...interface{}) error {
 		return err
 	}
 
-	if p.config.ArtifactoryToken == "" {
-		token := os.Getenv("ARTIFACTORY_TOKEN")
-		if token == "" {
-			log.Fatal("---> Missing Artifactory identity token. The token is required to complete tasks against Artifactory.")
-		}	
-	}
-
-	if p.config.ArtifactoryServer == "" {
-		serverApi := os.Getenv("ARTIFACTORY_SERVER")
-		if serverApi == "" {
-			log.Fatal("---> Missing Artifactory server API address. The server API address is required to communicate with Artifactory.")	
-		}
-	}
+	var errs *packersdk.MultiError
+	errs = packersdk.MultiErrorAppend(errs, p.config.ConnectionConfig.Prepare()...)
 
 	if p.config.SourcePath == "" {
-		log.Fatal("---> Please provide the source path to the artifact(s) to upload.")
-		log.Fatal("Source path should be in the form of either 'h:\\lab\\' or '/lab/'")
+		errs = packersdk.MultiErrorAppend(errs, errors.New("Please provide the 'source_path' to the artifact(s) to upload. "+
+			"Source path should be in the form of either 'h:\\lab\\' or '/lab/'"))
 	}
 
 	if p.config.ArtifactoryPath == "" {
-		log.Fatal("---> Please provide the Artifactory /repo/folder/path where the artifact(s) should be uploaded to.")
+		errs = packersdk.MultiErrorAppend(errs, errors.New("Please provide the Artifactory /repo/folder/path where the artifact(s) should be uploaded to with 'artifactory_path'."))
 	}
 
 	if len(p.config.FileList) <= 0 {
-		log.Fatal("---> Please add one or more files to the file_list for upload.")
+		errs = packersdk.MultiErrorAppend(errs, errors.New("Please add one or more files to the 'file_list' for upload."))
 	}
 
+	if len(errs.Errors) > 0 {
+		return errs
+	}
 	return nil
 }
 
 func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, source packersdk.Artifact) (packersdk.Artifact, bool, bool, error) {
-	var token, serverApi, sourcePath, artifPath, result string
+	var sourcePath, artifPath, result string
 	var err error
 	var fileList, failList []string
 
-	if p.config.ArtifactoryToken == "" {
-		token = os.Getenv("ARTIFACTORY_TOKEN")
-	} else {
-		token = p.config.ArtifactoryToken
-	}
-	
-	if p.config.ArtifactoryServer == "" {
-		serverApi = os.Getenv("ARTIFACTORY_SERVER")
-	} else {
-		serverApi = p.config.ArtifactoryServer
-	}
+	artifClient := client.New(&p.config.ConnectionConfig)
 
 	if p.config.SourcePath != "" {
 		sourcePath = p.config.SourcePath
@@ -93,7 +75,7 @@ func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, source
 	}
 
 	for _, file := range fileList {
-		result, err = tasks.UploadGeneralArtifact(serverApi, token, sourcePath, artifPath, file)
+		result, err = artifClient.UploadGeneralArtifact(sourcePath, artifPath, file)
 		if result == "Failed" && err == nil {
 			log.Println("File not found: " + file)
 			failList = append(failList, file)
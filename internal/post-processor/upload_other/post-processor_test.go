@@ -0,0 +1,117 @@
+package artifactUploadOther
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"packer-plugin-artifactory/internal/testutil/fakeartifactory"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestPostProcessorConfigure(t *testing.T) {
+	t.Setenv("ARTIFACTORY_TOKEN", "")
+	t.Setenv("ARTIFACTORY_SERVER", "")
+
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		wantErr []string
+	}{
+		{
+			name: "valid",
+			config: map[string]interface{}{
+				"artifactory_token":  "token",
+				"artifactory_server": "https://server.com/artifactory/api",
+				"source_path":        "/lab/",
+				"artifactory_path":   "/repo/folder/",
+				"file_list":          []string{"file1.txt"},
+			},
+		},
+		{
+			name:    "missing everything",
+			config:  map[string]interface{}{},
+			wantErr: []string{"artifactory_token", "artifactory_server", "source_path", "artifactory_path", "file_list"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &PostProcessor{}
+			err := p.Configure(tt.config)
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			for _, field := range tt.wantErr {
+				if !strings.Contains(err.Error(), field) {
+					t.Errorf("expected %q to be reported, got: %s", field, err)
+				}
+			}
+		})
+	}
+}
+
+func TestPostProcess(t *testing.T) {
+	sourceDir := t.TempDir()
+	for _, file := range []string{"file1.txt", "file2.txt"} {
+		if err := os.WriteFile(filepath.Join(sourceDir, file), []byte(file), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		fileList  []string
+		wantPaths []string
+		wantErr   bool
+	}{
+		{
+			name:      "upload several files",
+			fileList:  []string{"file1.txt", "file2.txt"},
+			wantPaths: []string{"/generic/tools/file1.txt", "/generic/tools/file2.txt"},
+		},
+		{
+			name:      "missing file",
+			fileList:  []string{"file1.txt", "file3.txt"},
+			wantPaths: []string{"/generic/tools/file1.txt"},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := fakeartifactory.New(t)
+
+			p := &PostProcessor{}
+			err := p.Configure(map[string]interface{}{
+				"artifactory_token":  fakeartifactory.Token,
+				"artifactory_server": server.ApiUrl(),
+				"source_path":        sourceDir,
+				"artifactory_path":   "/generic/tools/",
+				"file_list":          tt.fileList,
+			})
+			if err != nil {
+				t.Fatalf("Configure() error = %s", err)
+			}
+
+			_, _, _, err = p.PostProcess(context.Background(), packersdk.TestUi(t), &packersdk.MockArtifact{})
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("PostProcess() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for _, path := range tt.wantPaths {
+				if _, ok := server.Artifact(path); !ok {
+					t.Errorf("expected %s to be uploaded, requests: %v", path, server.Requests())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,351 @@
+package fakeartifactory
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// aqlQuery is the subset of Artifactory Query Language the fake understands:
+//
+//	items.find({...}).include("...").sort({"$desc": ["..."]}).offset(n).limit(n)
+//
+// Criteria support $and/$or, and $eq, $ne, $match, $nmatch, $gt, $gte, $lt and $lte on the item fields
+// (repo, path, name, type, size, created, modified, updated, created_by, modified_by, sha256, actual_sha1,
+// actual_md5) and on properties ("@key"). For properties, $ne and $nmatch also match items without the key.
+type aqlQuery struct {
+	criteria map[string]interface{}
+	include  []string
+	sortBy   []string
+	sortDesc bool
+	offset   int
+	limit    int
+}
+
+var defaultAqlFields = []string{"repo", "path", "name", "type", "size", "created", "created_by", "modified", "modified_by", "updated"}
+
+func parseAql(text string) (*aqlQuery, error) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "items.find") {
+		return nil, fmt.Errorf("only items.find queries are supported")
+	}
+	text = strings.TrimPrefix(text, "items")
+
+	query := &aqlQuery{criteria: map[string]interface{}{}, limit: -1}
+	for text != "" {
+		if text[0] != '.' {
+			return nil, fmt.Errorf("unexpected %q", text)
+		}
+		open := strings.Index(text, "(")
+		if open < 0 {
+			return nil, fmt.Errorf("missing '(' in %q", text)
+		}
+		method := text[1:open]
+		arg, rest, err := balanced(text[open:])
+		if err != nil {
+			return nil, err
+		}
+		text = strings.TrimSpace(rest)
+		arg = strings.TrimSpace(arg)
+
+		switch method {
+		case "find":
+			if arg != "" {
+				if err := json.Unmarshal([]byte(arg), &query.criteria); err != nil {
+					return nil, fmt.Errorf("find: %s", err)
+				}
+			}
+		case "include":
+			if err := json.Unmarshal([]byte("["+arg+"]"), &query.include); err != nil {
+				return nil, fmt.Errorf("include: %s", err)
+			}
+		case "sort":
+			var order map[string][]string
+			if err := json.Unmarshal([]byte(arg), &order); err != nil {
+				return nil, fmt.Errorf("sort: %s", err)
+			}
+			if fields, ok := order["$desc"]; ok {
+				query.sortBy, query.sortDesc = fields, true
+			} else {
+				query.sortBy = order["$asc"]
+			}
+		case "offset", "limit":
+			n, err := strconv.Atoi(arg)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", method, err)
+			}
+			if method == "offset" {
+				query.offset = n
+			} else {
+				query.limit = n
+			}
+		default:
+			return nil, fmt.Errorf("unsupported method %q", method)
+		}
+	}
+	return query, nil
+}
+
+// balanced returns the contents of the parenthesis at the start of text and whatever follows it.
+func balanced(text string) (string, string, error) {
+	depth := 0
+	inString := false
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case inString && c == '\\':
+			i++
+		case c == '"':
+			inString = !inString
+		case inString:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return text[1:i], text[i+1:], nil
+			}
+		}
+	}
+	return "", "", fmt.Errorf("unbalanced parenthesis")
+}
+
+func (q *aqlQuery) matches(item *Item) bool {
+	return matchCriteria(item, q.criteria)
+}
+
+func matchCriteria(item *Item, criteria map[string]interface{}) bool {
+	for key, cond := range criteria {
+		switch key {
+		case "$and", "$or":
+			clauses, _ := cond.([]interface{})
+			matchedAny := false
+			for _, clause := range clauses {
+				sub, _ := clause.(map[string]interface{})
+				matched := matchCriteria(item, sub)
+				if key == "$and" && !matched {
+					return false
+				}
+				matchedAny = matchedAny || matched
+			}
+			if key == "$or" && !matchedAny && len(clauses) > 0 {
+				return false
+			}
+		default:
+			if !matchField(item, key, cond) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func matchField(item *Item, field string, cond interface{}) bool {
+	ops, ok := cond.(map[string]interface{})
+	if !ok {
+		ops = map[string]interface{}{"$eq": cond}
+	}
+
+	for op, want := range ops {
+		if strings.HasPrefix(field, "@") {
+			if !matchProperty(item.Properties[strings.TrimPrefix(field, "@")], op, fmt.Sprint(want)) {
+				return false
+			}
+			continue
+		}
+		if !compare(fieldValue(item, field), op, want) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchProperty(values []string, op, want string) bool {
+	switch op {
+	case "$ne", "$nmatch":
+		positive := map[string]string{"$ne": "$eq", "$nmatch": "$match"}[op]
+		for _, value := range values {
+			if compare(value, positive, want) {
+				return false
+			}
+		}
+		return true
+	default:
+		for _, value := range values {
+			if compare(value, op, want) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func fieldValue(item *Item, field string) interface{} {
+	switch field {
+	case "repo":
+		return item.Repo
+	case "path":
+		return item.Path
+	case "name":
+		return item.Name
+	case "type":
+		return "file"
+	case "size":
+		return float64(len(item.Content))
+	case "created":
+		return item.Created
+	case "modified", "updated":
+		return item.Modified
+	case "created_by":
+		return item.CreatedBy
+	case "modified_by":
+		return item.ModifiedBy
+	case "sha256":
+		return item.Sha256()
+	case "actual_sha1":
+		return item.Sha1()
+	case "actual_md5":
+		return item.Md5()
+	}
+	return nil
+}
+
+// compare applies an AQL comparison operator. Times and sizes compare by value, everything else as strings.
+func compare(have interface{}, op string, want interface{}) bool {
+	var cmp int
+	switch h := have.(type) {
+	case time.Time:
+		w, err := parseTime(fmt.Sprint(want))
+		if err != nil {
+			return false
+		}
+		cmp = h.Compare(w)
+	case float64:
+		w, err := strconv.ParseFloat(fmt.Sprint(want), 64)
+		if err != nil {
+			return false
+		}
+		switch {
+		case h < w:
+			cmp = -1
+		case h > w:
+			cmp = 1
+		}
+	case string:
+		if op == "$match" || op == "$nmatch" {
+			return matchWildcard(fmt.Sprint(want), h) == (op == "$match")
+		}
+		cmp = strings.Compare(h, fmt.Sprint(want))
+	default:
+		return false
+	}
+
+	switch op {
+	case "$eq":
+		return cmp == 0
+	case "$ne":
+		return cmp != 0
+	case "$gt":
+		return cmp > 0
+	case "$gte":
+		return cmp >= 0
+	case "$lt":
+		return cmp < 0
+	case "$lte":
+		return cmp <= 0
+	}
+	return false
+}
+
+func parseTime(value string) (time.Time, error) {
+	for _, layout := range []string{TimeFormat, time.RFC3339Nano, "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unsupported date %q", value)
+}
+
+// sortKey renders a field so that string order matches value order.
+func sortKey(item *Item, field string) string {
+	switch value := fieldValue(item, field).(type) {
+	case time.Time:
+		return value.UTC().Format("2006-01-02T15:04:05.000000000")
+	case float64:
+		return fmt.Sprintf("%020.0f", value)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+func (q *aqlQuery) results(items []*Item) []map[string]interface{} {
+	sort.Slice(items, func(i, j int) bool {
+		for _, field := range q.sortBy {
+			a, b := sortKey(items[i], field), sortKey(items[j], field)
+			if a != b {
+				return (a < b) != q.sortDesc
+			}
+		}
+		return items[i].RepoPath() < items[j].RepoPath()
+	})
+
+	if q.offset > 0 {
+		if q.offset >= len(items) {
+			items = nil
+		} else {
+			items = items[q.offset:]
+		}
+	}
+	if q.limit >= 0 && q.limit < len(items) {
+		items = items[:q.limit]
+	}
+
+	fields := defaultAqlFields
+	withProps := false
+	if len(q.include) > 0 {
+		fields = nil
+		for _, field := range q.include {
+			switch {
+			case field == "*":
+				fields = append(fields, defaultAqlFields...)
+				fields = append(fields, "sha256", "actual_sha1", "actual_md5")
+			case field == "property" || strings.HasPrefix(field, "property.") || strings.HasPrefix(field, "@"):
+				withProps = true
+			default:
+				fields = append(fields, field)
+			}
+		}
+	}
+
+	results := []map[string]interface{}{}
+	for _, item := range items {
+		result := map[string]interface{}{}
+		for _, field := range fields {
+			switch value := fieldValue(item, field).(type) {
+			case time.Time:
+				result[field] = value.Format(TimeFormat)
+			case nil:
+			default:
+				result[field] = value
+			}
+		}
+		if withProps {
+			props := []map[string]string{}
+			keys := make([]string, 0, len(item.Properties))
+			for key := range item.Properties {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				for _, value := range item.Properties[key] {
+					props = append(props, map[string]string{"key": key, "value": value})
+				}
+			}
+			result["properties"] = props
+		}
+		results = append(results, result)
+	}
+	return results
+}
@@ -0,0 +1,692 @@
+// Package fakeartifactory is an in-memory stand-in for the parts of the Artifactory REST API the plugin uses:
+// storage (item info, folder and file listings, properties), quick/property/AQL search, deploy, and download.
+// It lets the datasources and post-processors be tested without a live server.
+package fakeartifactory
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Token is the only identity token the server accepts.
+const Token = "fake-artifactory-token"
+
+// TimeFormat is how Artifactory renders dates in its storage and AQL responses.
+const TimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// Item is a file stored in the fake server.
+type Item struct {
+	Repo       string
+	Path       string // folder within the repo; "." for the repo root
+	Name       string
+	Content    []byte
+	Properties map[string][]string
+	Created    time.Time
+	Modified   time.Time
+	CreatedBy  string
+	ModifiedBy string
+}
+
+// RepoPath is the /repo/folder/name path of the item.
+func (i *Item) RepoPath() string {
+	if i.Path == "." {
+		return "/" + i.Repo + "/" + i.Name
+	}
+	return "/" + i.Repo + "/" + i.Path + "/" + i.Name
+}
+
+// Sha256 of the item's content.
+func (i *Item) Sha256() string {
+	sum := sha256.Sum256(i.Content)
+	return hex.EncodeToString(sum[:])
+}
+
+// Sha1 of the item's content.
+func (i *Item) Sha1() string {
+	sum := sha1.Sum(i.Content)
+	return hex.EncodeToString(sum[:])
+}
+
+// Md5 of the item's content.
+func (i *Item) Md5() string {
+	sum := md5.Sum(i.Content)
+	return hex.EncodeToString(sum[:])
+}
+
+// Server is a running fake Artifactory.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	items    map[string]*Item // keyed by repo path without the leading slash
+	repos    map[string]bool
+	clock    time.Time
+	requests []string
+	ranges   []string
+	queries  []string
+
+	// Downloads left to cut off, and how many bytes of the body to send before cutting them off
+	interrupts     int
+	interruptAfter int
+	// Range requests left to answer from the start of the file
+	misalignedRanges int
+}
+
+// New starts a fake server that is shut down when the test finishes.
+func New(t testing.TB) *Server {
+	s := &Server{
+		items: map[string]*Item{},
+		repos: map[string]bool{},
+		clock: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// BaseUrl is the Artifactory base address, ex: http://127.0.0.1:1234/artifactory
+func (s *Server) BaseUrl() string {
+	return s.URL + "/artifactory"
+}
+
+// ApiUrl is the value to use for 'artifactory_server', ex: http://127.0.0.1:1234/artifactory/api
+func (s *Server) ApiUrl() string {
+	return s.BaseUrl() + "/api"
+}
+
+// StorageUrl is the artifact URI of the /repo/folder/file path.
+func (s *Server) StorageUrl(repoPath string) string {
+	return s.ApiUrl() + "/storage/" + escapePath(repoPath)
+}
+
+// DownloadUrl is the download URI of the /repo/folder/file path.
+func (s *Server) DownloadUrl(repoPath string) string {
+	return s.BaseUrl() + "/" + escapePath(repoPath)
+}
+
+// escapePath escapes each folder and file name of the path, the way the client does.
+func escapePath(repoPath string) string {
+	segments := strings.Split(strings.TrimLeft(repoPath, "/"), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// AddArtifact stores a file at the /repo/folder/file path with the given properties.
+// Each artifact added is created one minute after the previous one, so creation order is predictable.
+func (s *Server) AddArtifact(repoPath string, content []byte, props map[string]string) *Item {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.clock = s.clock.Add(time.Minute)
+	item := s.putItem(repoPath, content, s.clock)
+	for key, value := range props {
+		item.Properties[key] = []string{value}
+	}
+	return item
+}
+
+// Artifact returns the stored file at the /repo/folder/file path.
+func (s *Server) Artifact(repoPath string) (*Item, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item, ok := s.items[strings.TrimLeft(repoPath, "/")]
+	return item, ok
+}
+
+// Requests lists every request received so far as "METHOD /path?query".
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// Ranges lists the Range header of every download request that had one.
+func (s *Server) Ranges() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.ranges...)
+}
+
+// AqlQueries lists the body of every AQL search received so far.
+func (s *Server) AqlQueries() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.queries...)
+}
+
+// InterruptDownloads makes the next count downloads drop the connection after sending afterBytes of the body,
+// the way a flaky network would.
+func (s *Server) InterruptDownloads(count, afterBytes int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.interrupts = count
+	s.interruptAfter = afterBytes
+}
+
+// MisalignRanges makes the next count Range requests be answered from the start of the file instead of the
+// requested byte, with a 206 whose Content-Range says so, the way some proxies do.
+func (s *Server) MisalignRanges(count int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.misalignedRanges = count
+}
+
+// interruptingWriter sends up to remaining bytes of the body, then aborts the response.
+type interruptingWriter struct {
+	http.ResponseWriter
+	remaining int
+}
+
+func (w *interruptingWriter) Write(p []byte) (int, error) {
+	if len(p) > w.remaining {
+		w.ResponseWriter.Write(p[:w.remaining])
+		w.ResponseWriter.(http.Flusher).Flush()
+		panic(http.ErrAbortHandler)
+	}
+	w.remaining -= len(p)
+	return w.ResponseWriter.Write(p)
+}
+
+func (s *Server) putItem(repoPath string, content []byte, when time.Time) *Item {
+	key := strings.Trim(repoPath, "/")
+	segments := strings.Split(key, "/")
+	folder := "."
+	if len(segments) > 2 {
+		folder = strings.Join(segments[1:len(segments)-1], "/")
+	}
+
+	item, ok := s.items[key]
+	if !ok {
+		item = &Item{
+			Repo:       segments[0],
+			Path:       folder,
+			Name:       segments[len(segments)-1],
+			Properties: map[string][]string{},
+			Created:    when,
+			CreatedBy:  "admin",
+		}
+		s.items[key] = item
+	}
+	item.Content = content
+	item.Modified = when
+	item.ModifiedBy = "admin"
+	s.repos[item.Repo] = true
+	return item
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.RequestURI())
+	s.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer "+Token {
+		writeError(w, http.StatusUnauthorized, "Bad credentials")
+		return
+	}
+
+	switch p := r.URL.EscapedPath(); {
+	case strings.HasPrefix(p, "/artifactory/api/storage/"):
+		s.serveStorage(w, r, strings.TrimPrefix(p, "/artifactory/api/storage/"))
+	case p == "/artifactory/api/search/artifact":
+		s.serveQuickSearch(w, r)
+	case p == "/artifactory/api/search/prop":
+		s.servePropSearch(w, r)
+	case p == "/artifactory/api/search/aql":
+		s.serveAql(w, r)
+	case strings.HasPrefix(p, "/artifactory/api/repositories/"):
+		s.serveRepositories(w, r, strings.TrimPrefix(p, "/artifactory/api/repositories/"))
+	case strings.HasPrefix(p, "/artifactory/api/"):
+		writeError(w, http.StatusNotFound, "Unsupported API")
+	case strings.HasPrefix(p, "/artifactory/"):
+		s.serveRepoContent(w, r, strings.TrimPrefix(p, "/artifactory/"))
+	default:
+		writeError(w, http.StatusNotFound, "Not Found")
+	}
+}
+
+// queryParam finds a raw query parameter without url.ParseQuery, which rejects the ';' separators Artifactory
+// uses between properties.
+func queryParam(rawQuery, name string) (string, bool) {
+	for _, part := range strings.Split(rawQuery, "&") {
+		key, value, _ := strings.Cut(part, "=")
+		if key == name {
+			unescaped, err := url.QueryUnescape(value)
+			if err != nil {
+				unescaped = value
+			}
+			return unescaped, true
+		}
+	}
+	return "", false
+}
+
+func (s *Server) serveStorage(w http.ResponseWriter, r *http.Request, escapedPath string) {
+	repoPath, err := url.PathUnescape(escapedPath)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	key := strings.Trim(repoPath, "/")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if props, ok := queryParam(r.URL.RawQuery, "properties"); ok {
+		s.serveProperties(w, r, key, props)
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if item, ok := s.items[key]; ok {
+		writeJSON(w, http.StatusOK, s.itemInfo(item))
+		return
+	}
+
+	children := s.folderContents(key)
+	if children == nil {
+		writeError(w, http.StatusNotFound, "Unable to find item")
+		return
+	}
+
+	if _, ok := queryParam(r.URL.RawQuery, "list"); ok {
+		deep, _ := queryParam(r.URL.RawQuery, "deep")
+		writeJSON(w, http.StatusOK, s.fileList(key, deep == "1"))
+		return
+	}
+	writeJSON(w, http.StatusOK, s.folderInfo(key, children))
+}
+
+func (s *Server) serveProperties(w http.ResponseWriter, r *http.Request, key, props string) {
+	item, ok := s.items[key]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Unable to find item")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		found := map[string][]string{}
+		if props == "" {
+			found = item.Properties
+		} else {
+			for _, name := range strings.Split(props, ",") {
+				if values, ok := item.Properties[name]; ok {
+					found[name] = values
+				}
+			}
+		}
+		if len(found) == 0 {
+			writeError(w, http.StatusNotFound, "No properties could be found.")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"properties": found, "uri": s.StorageUrl(item.RepoPath())})
+
+	case http.MethodPut:
+		for _, pair := range strings.Split(props, ";") {
+			name, values, _ := strings.Cut(pair, "=")
+			if name == "" {
+				continue
+			}
+			item.Properties[name] = strings.Split(values, ",")
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	case http.MethodDelete:
+		for _, name := range strings.Split(props, ",") {
+			delete(item.Properties, name)
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+type child struct {
+	Uri    string `json:"uri"`
+	Folder bool   `json:"folder"`
+}
+
+// folderContents returns the direct children of a repo or folder key, or nil if it holds nothing.
+func (s *Server) folderContents(key string) []child {
+	prefix := key + "/"
+	seen := map[string]bool{}
+	var children []child
+	for itemKey := range s.items {
+		if !strings.HasPrefix(itemKey, prefix) {
+			continue
+		}
+		rest := strings.TrimPrefix(itemKey, prefix)
+		name, _, isFolder := strings.Cut(rest, "/")
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		children = append(children, child{Uri: "/" + name, Folder: isFolder})
+	}
+	if children == nil && s.repos[key] {
+		return []child{}
+	}
+	sort.Slice(children, func(i, j int) bool { return children[i].Uri < children[j].Uri })
+	return children
+}
+
+func (s *Server) folderInfo(key string, children []child) map[string]interface{} {
+	repo, folder, _ := strings.Cut(key, "/")
+	return map[string]interface{}{
+		"repo":         repo,
+		"path":         "/" + folder,
+		"created":      s.clock.Format(TimeFormat),
+		"lastModified": s.clock.Format(TimeFormat),
+		"lastUpdated":  s.clock.Format(TimeFormat),
+		"children":     children,
+		"uri":          s.StorageUrl(key),
+	}
+}
+
+func (s *Server) fileList(key string, deep bool) map[string]interface{} {
+	prefix := key + "/"
+	files := []map[string]interface{}{}
+	var keys []string
+	for itemKey := range s.items {
+		if strings.HasPrefix(itemKey, prefix) {
+			keys = append(keys, itemKey)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, itemKey := range keys {
+		rest := strings.TrimPrefix(itemKey, prefix)
+		if !deep && strings.Contains(rest, "/") {
+			continue
+		}
+		item := s.items[itemKey]
+		files = append(files, map[string]interface{}{
+			"uri":          "/" + rest,
+			"size":         len(item.Content),
+			"lastModified": item.Modified.Format(TimeFormat),
+			"folder":       false,
+			"sha1":         item.Sha1(),
+			"sha2":         item.Sha256(),
+		})
+	}
+	return map[string]interface{}{
+		"uri":     s.StorageUrl(key),
+		"created": s.clock.Format(TimeFormat),
+		"files":   files,
+	}
+}
+
+func (s *Server) itemInfo(item *Item) map[string]interface{} {
+	checksums := map[string]string{"sha1": item.Sha1(), "md5": item.Md5(), "sha256": item.Sha256()}
+	repoPath := strings.TrimPrefix(item.RepoPath(), "/"+item.Repo)
+	return map[string]interface{}{
+		"repo":              item.Repo,
+		"path":              repoPath,
+		"created":           item.Created.Format(TimeFormat),
+		"createdBy":         item.CreatedBy,
+		"lastModified":      item.Modified.Format(TimeFormat),
+		"modifiedBy":        item.ModifiedBy,
+		"lastUpdated":       item.Modified.Format(TimeFormat),
+		"downloadUri":       s.DownloadUrl(item.RepoPath()),
+		"mimeType":          "application/octet-stream",
+		"size":              strconv.Itoa(len(item.Content)),
+		"checksums":         checksums,
+		"originalChecksums": checksums,
+		"uri":               s.StorageUrl(item.RepoPath()),
+	}
+}
+
+type searchResult struct {
+	Uri string `json:"uri"`
+}
+
+func (s *Server) writeSearchResults(w http.ResponseWriter, items []*Item) {
+	sort.Slice(items, func(i, j int) bool { return items[i].RepoPath() < items[j].RepoPath() })
+	results := []searchResult{}
+	for _, item := range items {
+		results = append(results, searchResult{Uri: s.StorageUrl(item.RepoPath())})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"results": results})
+}
+
+func inRepos(item *Item, rawQuery string) bool {
+	repos, ok := queryParam(rawQuery, "repos")
+	if !ok || repos == "" {
+		return true
+	}
+	for _, repo := range strings.Split(repos, ",") {
+		if repo == item.Repo {
+			return true
+		}
+	}
+	return false
+}
+
+// serveQuickSearch matches names containing the search term, case insensitively, or the wildcard pattern if
+// the term includes '*' or '?'.
+func (s *Server) serveQuickSearch(w http.ResponseWriter, r *http.Request) {
+	name, _ := queryParam(r.URL.RawQuery, "name")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var found []*Item
+	for _, item := range s.items {
+		if !inRepos(item, r.URL.RawQuery) {
+			continue
+		}
+		if strings.ContainsAny(name, "*?") {
+			if matchWildcard(name, item.Name) {
+				found = append(found, item)
+			}
+		} else if strings.Contains(strings.ToLower(item.Name), strings.ToLower(name)) {
+			found = append(found, item)
+		}
+	}
+	s.writeSearchResults(w, found)
+}
+
+// servePropSearch returns items that have every requested property; a comma separated value matches any of
+// the values, and an empty value only checks the key exists.
+func (s *Server) servePropSearch(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var found []*Item
+	for _, item := range s.items {
+		if !inRepos(item, r.URL.RawQuery) {
+			continue
+		}
+		matched := true
+		for _, part := range strings.Split(r.URL.RawQuery, "&") {
+			name, value, _ := strings.Cut(part, "=")
+			name, _ = url.QueryUnescape(name)
+			value, _ = url.QueryUnescape(value)
+			if name == "" || name == "repos" {
+				continue
+			}
+			values, ok := item.Properties[name]
+			if !ok || (value != "" && !anyEqual(values, strings.Split(value, ","))) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			found = append(found, item)
+		}
+	}
+	s.writeSearchResults(w, found)
+}
+
+func anyEqual(have, want []string) bool {
+	for _, h := range have {
+		for _, w := range want {
+			if h == w {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *Server) serveAql(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	s.queries = append(s.queries, string(body))
+	s.mu.Unlock()
+
+	query, err := parseAql(string(body))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Failed to parse query: "+err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var found []*Item
+	for _, item := range s.items {
+		if query.matches(item) {
+			found = append(found, item)
+		}
+	}
+	results := query.results(found)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"results": results,
+		"range":   map[string]int{"start_pos": 0, "end_pos": len(results), "total": len(results)},
+	})
+}
+
+func (s *Server) serveRepositories(w http.ResponseWriter, r *http.Request, repo string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		s.repos[repo] = true
+		w.WriteHeader(http.StatusOK)
+	case http.MethodDelete:
+		delete(s.repos, repo)
+		for key, item := range s.items {
+			if item.Repo == repo {
+				delete(s.items, key)
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// serveRepoContent handles deploys (PUT, with optional ';key=value' matrix properties) and downloads (GET/HEAD,
+// with Range support).
+func (s *Server) serveRepoContent(w http.ResponseWriter, r *http.Request, escapedPath string) {
+	repoPath, err := url.PathUnescape(escapedPath)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		segments := strings.Split(repoPath, ";")
+		content, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		s.mu.Lock()
+		s.clock = s.clock.Add(time.Minute)
+		item := s.putItem(segments[0], content, s.clock)
+		for _, pair := range segments[1:] {
+			name, value, _ := strings.Cut(pair, "=")
+			item.Properties[name] = strings.Split(value, ",")
+		}
+		info := s.itemInfo(item)
+		s.mu.Unlock()
+		writeJSON(w, http.StatusCreated, info)
+
+	case http.MethodGet, http.MethodHead:
+		s.mu.Lock()
+		item, ok := s.items[strings.Trim(repoPath, "/")]
+		if rangeHeader := r.Header.Get("Range"); rangeHeader != "" {
+			s.ranges = append(s.ranges, rangeHeader)
+			if s.misalignedRanges > 0 {
+				s.misalignedRanges--
+				r.Header.Set("Range", "bytes=0-")
+			}
+		}
+		if ok && r.Method == http.MethodGet && s.interrupts > 0 {
+			s.interrupts--
+			w = &interruptingWriter{ResponseWriter: w, remaining: s.interruptAfter}
+		}
+		s.mu.Unlock()
+		if !ok {
+			writeError(w, http.StatusNotFound, "File not found.")
+			return
+		}
+		w.Header().Set("X-Checksum-Sha256", item.Sha256())
+		w.Header().Set("X-Checksum-Sha1", item.Sha1())
+		w.Header().Set("X-Checksum-Md5", item.Md5())
+		w.Header().Set("ETag", item.Sha1())
+		w.Header().Set("Content-Type", "application/octet-stream")
+		http.ServeContent(w, r, item.Name, item.Modified, bytes.NewReader(item.Content))
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]interface{}{
+		"errors": []map[string]interface{}{{"status": status, "message": message}},
+	})
+}
+
+// matchWildcard matches Artifactory style patterns where '*' is any run of characters and '?' is one character.
+func matchWildcard(pattern, value string) bool {
+	expr := regexp.QuoteMeta(pattern)
+	expr = strings.ReplaceAll(expr, `\*`, ".*")
+	expr = strings.ReplaceAll(expr, `\?`, ".")
+	matched, _ := regexp.MatchString("^"+expr+"$", value)
+	return matched
+}
+
+// String renders a short description of the item, for test failure messages.
+func (i *Item) String() string {
+	return fmt.Sprintf("%s (%d bytes)", i.RepoPath(), len(i.Content))
+}
@@ -0,0 +1,110 @@
+package fakeartifactory
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func get(t *testing.T, s *Server, method, url, body string, header map[string]string) (*http.Response, []byte) {
+	t.Helper()
+	request, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.Header.Set("Authorization", "Bearer "+Token)
+	for k, v := range header {
+		request.Header.Set(k, v)
+	}
+	response, err := s.Client().Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	data, _ := io.ReadAll(response.Body)
+	return response, data
+}
+
+func TestServer_RequiresToken(t *testing.T) {
+	s := New(t)
+	response, err := http.Get(s.ApiUrl() + "/search/artifact?name=x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != http.StatusUnauthorized {
+		t.Errorf("status = %d, want 401", response.StatusCode)
+	}
+}
+
+func TestServer_Aql(t *testing.T) {
+	s := New(t)
+	s.AddArtifact("/images/win22/win22.ova", []byte("old"), map[string]string{"release": "stable"})
+	s.AddArtifact("/images/win22-core/win22-core.ova", []byte("newer"), map[string]string{"release": "stable", "revoked": "true"})
+	s.AddArtifact("/scratch/win22/win22.ova", []byte("newest"), map[string]string{"release": "testing"})
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"all ova", `items.find({"name":{"$match":"*.ova"}})`, []string{"win22-core.ova", "win22.ova", "win22.ova"}},
+		{"repo scoped", `items.find({"repo":"images","@release":"stable"}).sort({"$desc":["created"]})`, []string{"win22-core.ova", "win22.ova"}},
+		{"not revoked", `items.find({"repo":"images","@revoked":{"$ne":"true"}})`, []string{"win22.ova"}},
+		{"or", `items.find({"$or":[{"@release":"testing"},{"@revoked":"true"}]}).sort({"$asc":["created"]})`, []string{"win22-core.ova", "win22.ova"}},
+		{"created after", `items.find({"created":{"$gt":"2024-01-01T00:02:00.000Z"}})`, []string{"win22.ova"}},
+		{"limit", `items.find().sort({"$desc":["created"]}).limit(1)`, []string{"win22.ova"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, body := get(t, s, "POST", s.ApiUrl()+"/search/aql", tt.query, nil)
+			if response.StatusCode != http.StatusOK {
+				t.Fatalf("status = %d: %s", response.StatusCode, body)
+			}
+			var result struct {
+				Results []struct {
+					Name string `json:"name"`
+				} `json:"results"`
+			}
+			if err := json.Unmarshal(body, &result); err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, r := range result.Results {
+				names = append(names, r.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("got %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestServer_PropertiesAndDownload(t *testing.T) {
+	s := New(t)
+	s.AddArtifact("/repo/folder/file.txt", []byte("0123456789"), nil)
+
+	response, _ := get(t, s, "PUT", s.StorageUrl("/repo/folder/file.txt")+"?properties=release=stable;testing=passed", "", nil)
+	if response.StatusCode != http.StatusNoContent {
+		t.Fatalf("set properties status = %d", response.StatusCode)
+	}
+	item, _ := s.Artifact("/repo/folder/file.txt")
+	if item.Properties["release"][0] != "stable" || item.Properties["testing"][0] != "passed" {
+		t.Errorf("properties = %v", item.Properties)
+	}
+
+	response, body := get(t, s, "GET", s.DownloadUrl("/repo/folder/file.txt"), "", map[string]string{"Range": "bytes=4-"})
+	if response.StatusCode != http.StatusPartialContent || string(body) != "456789" {
+		t.Errorf("range download = %d %q", response.StatusCode, body)
+	}
+	if response.Header.Get("X-Checksum-Sha256") != item.Sha256() {
+		t.Errorf("missing sha256 header")
+	}
+
+	response, body = get(t, s, "GET", s.StorageUrl("/repo")+"?list&deep=1", "", nil)
+	if response.StatusCode != http.StatusOK || !strings.Contains(string(body), `"uri":"/folder/file.txt"`) {
+		t.Errorf("file list = %d %s", response.StatusCode, body)
+	}
+}